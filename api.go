@@ -77,42 +77,33 @@ func UnsafeTransform(lambda float64) ModelType {
 // Decompose performs a STL decomposition.
 //
 // Following the R library conventions, this function uses a default of 2 iterations and 0 robust iterations.
+// X must have at least two periods of observations. Shorter series are returned with an error, where they used to panic.
 func Decompose(X []float64, periodicity, width int, m ModelType, opts ...Opt) Result {
+	return new(workspace).decompose(X, Result{}, periodicity, width, m, opts)
+}
+
+// decompose performs a STL decomposition (see Decompose) with the buffers of the workspace, writing the components into dst (see newState).
+func (ws *workspace) decompose(X []float64, dst Result, periodicity, width int, m ModelType, opts []Opt) Result {
 	if periodicity < 2 {
 		return Result{Err: errors.Errorf("Periodicity must be greater than 2")}
 	}
 	if width < 1 {
 		return Result{Err: errors.Errorf("Width must be greater than 1")}
 	}
+	if len(X) < 2*periodicity {
+		return Result{Err: errors.Errorf("Expected at least %d observations (two periods). Got %d", 2*periodicity, len(X))}
+	}
 
 	bc := m.Fwd
 	ibc := m.Bwd
 
 	// transform the data
 	X = bc(X)
-	s := newState(X, periodicity, width, opts...)
-	var useResidualWeights bool
-	for o := 0; o <= s.robustIter; o++ {
-		useResidualWeights = o > 0
-		for i := 0; i < s.innerIter; i++ {
-			s.doDetrend()
-			if err := s.smoothSubcycles(useResidualWeights); err != nil {
-				s.Err = errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to smooth subcycles", o, i)
-				return s.Result
-			}
-			if err := s.removeSeasonality(); err != nil {
-				s.Err = errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to remove seasonality", o, i)
-				return s.Result
-			}
-			if err := s.updateSeasonalAndTrend(useResidualWeights); err != nil {
-				s.Err = errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to update seasonal and trend", o, i)
-				return s.Result
-			}
-
-		}
-		s.updateWeights()
+	s := ws.newState(X, dst, periodicity, width, opts...)
+	if err := s.iterate(); err != nil {
+		s.Err = err
+		return s.Result
 	}
-	updateResiduals(&s.Result)
 
 	// untransform the data
 	s.Result.Data = ibc(s.Result.Data)
@@ -121,3 +112,34 @@ func Decompose(X []float64, periodicity, width int, m ModelType, opts ...Opt) Re
 	s.Result.Resid = ibc(s.Result.Resid)
 	return s.Result
 }
+
+// DecomposeFractional performs a STL decomposition with a non-integer period, such as 52.18 for the yearly seasonality of weekly data,
+// or 365.25 for the yearly seasonality of daily data.
+//
+// Each observation is mapped to a continuous seasonal phase (its position within the cycle), and the seasonal component is smoothed
+// across cycles among observations of a similar phase. This prevents the seasonality from drifting over the years, as it would if the period were rounded.
+// The width is the width of the seasonal smoother in cycles, as with Decompose.
+func DecomposeFractional(X []float64, period float64, width int, m ModelType, opts ...Opt) Result {
+	if period < 2 {
+		return Result{Err: errors.Errorf("Period must be greater than 2")}
+	}
+	if width < 1 {
+		return Result{Err: errors.Errorf("Width must be greater than 1")}
+	}
+	if ext := int(math.Ceil(period)); len(X) < 2*ext {
+		return Result{Err: errors.Errorf("Expected at least %d observations for a period of %v. Got %d", 2*ext, period, len(X))}
+	}
+
+	X = m.Fwd(X)
+	s := newFractionalState(X, period, width, opts...)
+	if err := s.iterate(); err != nil {
+		s.Err = err
+		return s.Result
+	}
+
+	s.Result.Data = m.Bwd(s.Result.Data)
+	s.Result.Seasonal = m.Bwd(s.Result.Seasonal)
+	s.Result.Trend = m.Bwd(s.Result.Trend)
+	s.Result.Resid = m.Bwd(s.Result.Resid)
+	return s.Result
+}
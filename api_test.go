@@ -83,46 +83,46 @@ func Example() {
 	// │       ╰──╯         ╰╯
 	//
 	// Trend:
-	// │
-	// │+                                   ╭───╮       ╭──╮
-	// │                         ╭───╮    ╭─╯   ╰─╮  ╭──╯  ╰─╮
-	// │                       ╭─╯   ╰─╮╭─╯       ╰──╯       ╰╮
-	// │                      ╭╯       ╰╯                     ╰╮ ╭─────╮
-	// │                     ╭╯                                ╰─╯     ╰╮
-	// │                ╭╮  ╭╯                                          ╰─╮
-	// │             ╭──╯╰──╯                                             ╰╮   ╭───╮
-	// │           ╭─╯                                                     ╰───╯   ╰─╮
-	// │         ╭─╯                                                                 ╰╮
-	// │  ───────╯                                                                    ╰╮
-	// │                                                                               ╰─
+	// │+
+	// │                                                                               ╭─
+	// │                                                                     ╭─────────╯
+	// │                                                            ╭────────╯
+	// │                                                   ╭────────╯
+	// │                                             ╭─────╯
+	// │                                       ╭─────╯
+	// │                                ╭──────╯
+	// │                        ╭───────╯
+	// │                    ╭───╯
+	// │              ╭─────╯
+	// │  ────────────╯
 	//
 	// Seasonal:
 	// │
-	// │                                                                              ╭──
-	// │+                                                                        ╭────╯
-	// │                                                                     ╭───╯
-	// │                                                                 ╭───╯
-	// │                                                             ╭───╯
-	// │                                                          ╭──╯
-	// │                                                      ╭───╯
-	// │                                                ╭─────╯
-	// │  ─────╮                                   ╭────╯
-	// │       ╰──────╮                    ╭───────╯
-	// │              ╰────────────────────╯
+	// │   ╭╮          ╭╮          ╭╮          ╭╮          ╭╮          ╭╮          ╭╮
+	// │  ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮
+	// │  │  │        │  │        │  │        │  │        │  │        │  │        │  │
+	// │  ╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮
+	// │      │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │
+	// │      │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │
+	// │+     ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮
+	// │       │  │        │  │        │  │        │  │        │  │        │  │        │
+	// │       │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │
+	// │       ╰╮│         ╰╮│         ╰╮│         ╰╮│         ╰╮│         ╰╮│         ╰╮
+	// │        ╰╯          ╰╯          ╰╯          ╰╯          ╰╯          ╰╯          ╰
 	//
 	// Residuals:
-	// │
-	// │                           ╭╮                                  ╭╮
-	// │                           │╰╮         ╭╮          ╭╮          ││          ╭╮
-	// │               ╭─╮        ╭╯ │        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮
-	// │+ ╭──╮        ╭╯ │        │  ╰╮       │  │        │  ╰╮       │  │        │  │
-	// │  │  │       ╭╯  ╰╮       │   │      ╭╯  ╰╮      ╭╯   │      ╭╯  ╰╮      ╭╯  ╰╮
-	// │  │  ╰╮     ╭╯    │     ╭─╯   │     ╭╯    │     ╭╯    │     ╭╯    │    ╭─╯    │
-	// │  ╯   │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    │      │
-	// │      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮
-	// │       │  │        │ ╭╯        │  │        │ ╭╯        │ ╭╯        │  │        │
-	// │       ╰╮╭╯        ╰╮│         │ ╭╯        │╭╯         ╰╮│         │ ╭╯        │
-	// │        ╰╯          ╰╯         ╰─╯         ╰╯           ╰╯         ╰─╯         ╰─
+	// │+
+	// │      ╭╮                   ╭─╮                                 ╭╮
+	// │      ││                   │ ╰╮        ╭╮   ╭╮   ╭╮            ││
+	// │      │╰╮          ╭╮╭╮    │  │        ││   ││   ││    ╭╮      │╰╮     ╭╮
+	// │      │ ╰╮  ╭╮     ││││    │  │      ╭╮││   ││   ││  ╭╮││     ╭╯ │     │╰─╮   ╭╮╭
+	// │      │  │╭─╯╰╮ ╭╮ │││╰╮  ╭╯  │      ││││╭─╮│╰╮  │╰╮ ││││  ╭╮ │  │     │  │ ╭╮│││
+	// │  ╭╮  │  ││   │ ││ │╰╯ ╰╮ │   ╰╮  ╭╮╭╯││╰╯ ││ ╰╮ │ ╰─╯╰╯│╭─╯│╭╯  │    ╭╯  ╰─╯╰╯││
+	// │  ││  │  ││   │╭╯│ │    ╰─╯    │ ╭╯╰╯ ╰╯   ││  ╰─╯      ╰╯  ││   ╰╮ ╭─╯        ││
+	// │  ││  │  ╰╯   ╰╯ ╰─╯           ╰─╯         ││               ╰╯    ╰─╯          ╰╯
+	// │  ││╭─╯                                    ╰╯
+	// │  │╰╯
+	// │  ╯
 	//
 	// MULTIPLICATIVE MODEL
 	// =====================
@@ -142,44 +142,56 @@ func Example() {
 	//
 	// Trend:
 	// │+
-	// │                                                 ╭╮
-	// │                         ╭─╮        ╭────╮     ╭─╯╰─╮
-	// │                        ╭╯ ╰──╮   ╭─╯    ╰─╮ ╭─╯    ╰╮
-	// │                       ╭╯     ╰╮╭─╯        ╰─╯       ╰╮      ╭╮
-	// │                      ╭╯       ╰╯                     ╰──────╯╰╮
-	// │                     ╭╯                                        ╰─╮
-	// │             ╭─────╮╭╯                                           ╰╮      ╭╮
-	// │            ╭╯     ╰╯                                             ╰╮ ╭───╯╰─╮
-	// │           ╭╯                                                      ╰─╯      ╰╮
-	// │        ╭──╯                                                                 ╰╮
-	// │  ──────╯                                                                     ╰──
+	// │                                                                               ╭─
+	// │                                                                     ╭─────────╯
+	// │                                                            ╭────────╯
+	// │                                                   ╭────────╯
+	// │                                             ╭─────╯
+	// │                                       ╭─────╯
+	// │                                ╭──────╯
+	// │                        ╭───────╯
+	// │                    ╭───╯
+	// │               ╭────╯
+	// │  ─────────────╯
 	//
 	// Seasonal:
 	// │
-	// │                                                                           ╭─────
-	// │+                                                                      ╭───╯
-	// │                                                                   ╭───╯
-	// │                                                               ╭───╯
-	// │                                                           ╭───╯
-	// │                                                        ╭──╯
-	// │                                                   ╭────╯
-	// │                                             ╭─────╯
-	// │  ────────╮                             ╭────╯
-	// │          ╰──────────╮        ╭─────────╯
-	// │                     ╰────────╯
+	// │   ╭╮          ╭╮          ╭╮          ╭╮          ╭╮          ╭╮          ╭╮
+	// │  ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮
+	// │  │  │        │  │        │  │        │  │        │  │        │  │        │  │
+	// │  ╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮
+	// │      │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │     ╭╯    │
+	// │      │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │
+	// │+     │    │      │    │      │    │      │    │      │    │      │    │      │
+	// │      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮
+	// │       │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │ ╭╯        │
+	// │       ╰╮│         ╰╮│         ╰╮│         ╰╮│         │ │         │ │         │
+	// │        ╰╯          ╰╯          ╰╯          ╰╯         ╰─╯         ╰─╯         ╰─
 	//
 	// Residuals:
-	// │
-	// │                           ╭╮                                  ╭╮
-	// │                           │╰╮         ╭╮          ╭╮          ││          ╭╮
-	// │               ╭─╮        ╭╯ │         │╰╮        ╭╯╰╮        ╭╯╰╮        ╭╯╰╮
-	// │+ ╭──╮        ╭╯ │        │  ╰╮       ╭╯ │        │  │        │  │        │  │
-	// │  │  │       ╭╯  │        │   │      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮      ╭╯  ╰╮
-	// │  │  ╰╮     ╭╯   ╰╮     ╭─╯   │     ╭╯    │     ╭╯    │     ╭╯    │    ╭─╯    │
-	// │  ╯   │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    ╭╯     │    │      │
-	// │      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮  ╭╯      ╰╮
-	// │       │  │        │ ╭╯        │  │        │ ╭╯        │  │        │  │        │
-	// │       │ ╭╯        ╰╮│         │ ╭╯        │╭╯         ╰╮╭╯        │ ╭╯        │
-	// │       ╰─╯          ╰╯         ╰─╯         ╰╯           ╰╯         ╰─╯         ╰─
+	// │+
+	// │                                                               ╭╮
+	// │      ╭╮                   ╭─╮              ╭╮                 ││
+	// │      │╰╮            ╭╮    │ ╰╮        ╭╮   ││   ╭╮    ╭╮      │╰╮
+	// │      │ │   ╭╮     ╭╮││    │  │        ││   ││   ││  ╭╮││     ╭╯ │     ╭╮
+	// │      │ ╰╮  ││  ╭╮ ││││    │  │      ╭╮││ ╭╮││   │╰╮ ││││     │  │     │╰─╮ ╭╮╭╮╭
+	// │      │  │╭─╯╰╮ ││ │╰╯╰╮  ╭╯  ╰╮  ╭╮ │││╰─╯││╰╮  │ │ ││││╭╮╭╮ │  │    ╭╯  ╰─╯╰╯││
+	// │  ╭╮  │  ││   │ ││ │   ╰╮╭╯    │  ││╭╯╰╯   ││ ╰──╯ ╰─╯╰╯││╰╯│╭╯  ╰╮ ╭─╯        ││
+	// │  ││  │  ││   ╰─╯│╭╯    ╰╯     ╰──╯╰╯      ││           ╰╯  ╰╯    ╰─╯          ╰╯
+	// │  ││  │  ╰╯      ╰╯                        ╰╯
+	// │  ││╭─╯
+	// │  ╯╰╯
+}
+
+func ExampleWithTrendSlope() {
+	// a linear trend of 0.5 per observation, with a seasonality of period 4
+	data := make([]float64, 48)
+	for i := range data {
+		data[i] = 10 + 0.5*float64(i) + []float64{1, -1, 2, -2}[i%4]
+	}
+	res := check(stl.Decompose(data, 4, 7, stl.Additive(), stl.WithTrendSlope()))
+	fmt.Printf("Slope: %1.2f %1.2f %1.2f\n", res.TrendSlope[0], res.TrendSlope[24], res.TrendSlope[47])
 
+	// Output:
+	// Slope: 0.50 0.50 0.50
 }
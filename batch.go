@@ -0,0 +1,211 @@
+package stl
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/chewxy/stl/loess"
+	"github.com/pkg/errors"
+)
+
+// workspace holds the buffers of a decomposition, which are reused by the next decomposition (of a series of the same shape).
+// The components of the Result are not part of the workspace, as they are returned.
+type workspace struct {
+	weights          []float64
+	detrend          []float64
+	extendedSeasonal []float64
+	scstate          *subcycleState
+}
+
+// floats returns buf resliced to n zeroed elements, reallocating it if it is too small.
+func floats(buf *[]float64, n int) []float64 {
+	if cap(*buf) < n {
+		*buf = make([]float64, n)
+	}
+	*buf = (*buf)[:n]
+	for i := range *buf {
+		(*buf)[i] = 0
+	}
+	return *buf
+}
+
+// component returns dst zeroed if it has n elements. Otherwise it allocates a new slice.
+func component(dst []float64, n int) []float64 {
+	if len(dst) != n {
+		return make([]float64, n)
+	}
+	for i := range dst {
+		dst[i] = 0
+	}
+	return dst
+}
+
+// newState creates a state for decomposing data, reusing the buffers of the workspace.
+// The components of the result are written into those of dst, where they are of the right length.
+func (ws *workspace) newState(data []float64, dst Result, periodicity, width int, opts ...Opt) *state {
+	s := &state{
+		periodicity: periodicity,
+		width:       width,
+		smoothFn:    loess.Linear,
+
+		sConf: DefaultSeasonal(width),
+		tConf: DefaultTrend(periodicity, width),
+		lConf: DefaultLowPass(periodicity),
+
+		// R interface sets these to be the default
+		innerIter:  2,
+		robustIter: 0,
+	}
+	s.Data = data
+	s.Trend = component(dst.Trend, len(data))
+	s.Seasonal = component(dst.Seasonal, len(data))
+	s.Resid = component(dst.Resid, len(data))
+	s.Weights = component(dst.Weights, len(data))
+	s.weights = floats(&ws.weights, len(data))
+	s.extendedSeasonal = floats(&ws.extendedSeasonal, len(data)+2*periodicity)
+	s.detrend = floats(&ws.detrend, len(data))
+
+	for i := range s.weights {
+		s.weights[i] = 1
+	}
+
+	// the options have to be applied before the states are created, as they may change the configurations
+	for _, o := range opts {
+		o(s)
+	}
+	s.sstate = loess.NewWithExternal(s.tConf.Width, s.detrend, s.weights) // the trend is smoothed from the deseasonalized data (see updateSeasonalAndTrend)
+	s.sstate.SetKernel(s.tConf.Kernel)
+	s.sstate.SetFast(s.tConf.Fast)
+	s.sstate.SetInterpolation(s.tConf.Interpolation)
+
+	if sc := ws.scstate; sc != nil && sc.periodicity == periodicity && sc.periods == len(data)/periodicity && sc.rem == len(data)%periodicity {
+		sc.reset(s.sConf)
+	} else {
+		ws.scstate = newSubcycleState(s.sConf, len(s.Data), s.periodicity, 1, 1)
+	}
+	s.scstate = ws.scstate
+	s.scstate.parallel = s.parallel
+	return s
+}
+
+// Decomposer performs STL decompositions of series one after another, with the same periodicity, width, model and options.
+// It reuses its buffers from one series to the next, which saves most of the allocations when the series are of the same length.
+//
+// A Decomposer is not safe for concurrent use. Use one per goroutine.
+type Decomposer struct {
+	periodicity, width int
+	m                  ModelType
+	opts               []Opt
+
+	ws workspace
+}
+
+// NewDecomposer creates a Decomposer. The arguments are those of Decompose.
+func NewDecomposer(periodicity, width int, m ModelType, opts ...Opt) *Decomposer {
+	return &Decomposer{
+		periodicity: periodicity,
+		width:       width,
+		m:           m,
+		opts:        opts,
+	}
+}
+
+// Decompose decomposes X. The result is the same as that of Decompose, except that a panic in the decomposition
+// is returned as the error of the result.
+func (d *Decomposer) Decompose(X []float64) Result { return d.DecomposeInto(X, Result{}) }
+
+// DecomposeInto is like Decompose, except that the trend, seasonal and remainder components and the weights are written into those of dst,
+// if they have the same length as X. Those that do not are allocated.
+func (d *Decomposer) DecomposeInto(X []float64, dst Result) (retVal Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			// the buffers may be in any state
+			d.ws = workspace{}
+			retVal = Result{Err: errors.Errorf("Decomposition panicked: %v", r)}
+			if err, ok := r.(error); ok {
+				retVal.Err = errors.Wrap(err, "Decomposition panicked")
+			}
+		}
+	}()
+	return d.ws.decompose(X, dst, d.periodicity, d.width, d.m, d.opts)
+}
+
+// workerCount returns the number of workers to use. If workers <= 0, GOMAXPROCS is used.
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// DecomposeBatch performs a STL decomposition of each of the series, with the same periodicity, width, model and options.
+//
+// The series are decomposed concurrently by a pool of workers (GOMAXPROCS of them if workers <= 0).
+// Each worker has its own Decomposer, which reuses its buffers from one series to the next.
+// The ith result is that of the ith series, and is the same as that of Decompose. An error (or panic) in a decomposition is reported in
+// the Err field of its result, and does not stop the rest of the batch.
+//
+// As with Decompose, the series are transformed in place by the model.
+func DecomposeBatch(X [][]float64, periodicity, width int, m ModelType, workers int, opts ...Opt) []Result {
+	retVal := make([]Result, len(X))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workerCount(workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := NewDecomposer(periodicity, width, m, opts...)
+			for i := range jobs {
+				retVal[i] = d.Decompose(X[i])
+			}
+		}()
+	}
+	for i := range X {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return retVal
+}
+
+// DecomposeStream is like DecomposeBatch, except that the series are received from a channel, and the results sent on the returned channel
+// in the same order as the series were received. The returned channel is closed once in is closed and all its series have been decomposed.
+//
+// At most a few series per worker are in flight at any time, so the results must be received for the stream to make progress.
+func DecomposeStream(in <-chan []float64, periodicity, width int, m ModelType, workers int, opts ...Opt) <-chan Result {
+	type job struct {
+		X   []float64
+		res chan Result
+	}
+	n := workerCount(workers)
+	out := make(chan Result)
+	jobs := make(chan job, n)
+	pending := make(chan chan Result, n) // the results to be sent, in order
+
+	go func() {
+		for X := range in {
+			res := make(chan Result, 1)
+			pending <- res
+			jobs <- job{X, res}
+		}
+		close(jobs)
+		close(pending)
+	}()
+
+	for w := 0; w < n; w++ {
+		go func() {
+			d := NewDecomposer(periodicity, width, m, opts...)
+			for j := range jobs {
+				j.res <- d.Decompose(j.X)
+			}
+		}()
+	}
+
+	go func() {
+		for res := range pending {
+			out <- <-res
+		}
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,130 @@
+package stl
+
+import (
+	"math"
+	"testing"
+)
+
+// batchSeries returns variations of the CO2 data, including one that is too short to be decomposed.
+func batchSeries(t *testing.T) [][]float64 {
+	data := loadCO2(t)
+	var retVal [][]float64
+	for i := 0; i < 12; i++ {
+		X := make([]float64, len(data)-12*(i%3))
+		for j := range X {
+			X[j] = data[j]*(1+0.01*float64(i)) + math.Sin(float64(i*j))
+		}
+		retVal = append(retVal, X)
+	}
+	return append(retVal, []float64{1, 2, 3, 4, 5})
+}
+
+func copySeries(X [][]float64) [][]float64 {
+	retVal := make([][]float64, len(X))
+	for i := range X {
+		retVal[i] = append([]float64(nil), X[i]...)
+	}
+	return retVal
+}
+
+func sameResult(t *testing.T, i int, expected, got Result) {
+	if (expected.Err == nil) != (got.Err == nil) || (expected.Err != nil && expected.Err.Error() != got.Err.Error()) {
+		t.Errorf("Series %d: expected error %v. Got %v", i, expected.Err, got.Err)
+		return
+	}
+	for j := range expected.Trend {
+		if expected.Trend[j] != got.Trend[j] || expected.Seasonal[j] != got.Seasonal[j] || expected.Resid[j] != got.Resid[j] {
+			t.Errorf("Series %d differs from Decompose at %d", i, j)
+			return
+		}
+	}
+}
+
+func TestDecomposeBatch(t *testing.T) {
+	series := batchSeries(t)
+	opts := []Opt{WithRobustIter(2)}
+	for _, m := range []ModelType{Additive(), Multiplicative()} {
+		batch := DecomposeBatch(copySeries(series), 12, 35, m, 3, opts...)
+		if len(batch) != len(series) {
+			t.Fatalf("Expected %d results. Got %d", len(series), len(batch))
+		}
+		for i, X := range copySeries(series) {
+			if i == len(series)-1 {
+				if batch[i].Err == nil {
+					t.Errorf("Expected an error for the short series")
+				}
+				continue
+			}
+			sameResult(t, i, Decompose(X, 12, 35, m, opts...), batch[i])
+		}
+	}
+}
+
+func TestDecomposeStream(t *testing.T) {
+	series := batchSeries(t)
+	in := make(chan []float64)
+	go func() {
+		for _, X := range copySeries(series) {
+			in <- X
+		}
+		close(in)
+	}()
+
+	var i int
+	for res := range DecomposeStream(in, 12, 35, Additive(), 4) {
+		if i == len(series)-1 {
+			if res.Err == nil {
+				t.Errorf("Expected an error for the short series")
+			}
+		} else {
+			X := append([]float64(nil), series[i]...)
+			sameResult(t, i, Decompose(X, 12, 35, Additive()), res)
+		}
+		i++
+	}
+	if i != len(series) {
+		t.Errorf("Expected %d results. Got %d", len(series), i)
+	}
+}
+
+func TestDecomposerInto(t *testing.T) {
+	data := loadCO2(t)
+	d := NewDecomposer(12, 35, Additive())
+	dst := Result{Trend: make([]float64, len(data)), Seasonal: make([]float64, len(data)), Resid: make([]float64, len(data))}
+	for i := range dst.Trend {
+		dst.Trend[i] = math.NaN() // stale values must not leak into the decomposition
+	}
+	res := d.DecomposeInto(append([]float64(nil), data...), dst)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if &res.Trend[0] != &dst.Trend[0] || &res.Seasonal[0] != &dst.Seasonal[0] || &res.Resid[0] != &dst.Resid[0] {
+		t.Errorf("Expected the components to be written into dst")
+	}
+	sameResult(t, 0, Decompose(append([]float64(nil), data...), 12, 35, Additive()), res)
+
+	if res = d.Decompose([]float64{1, 2, 3}); res.Err == nil {
+		t.Errorf("Expected an error for a series that is too short")
+	}
+}
+
+func TestDecomposeShort(t *testing.T) {
+	// fewer than two periods cannot be decomposed: the cycle-subseries would have a single observation
+	X := make([]float64, 23)
+	for i := range X {
+		X[i] = float64(i % 12)
+	}
+	const msg = "Expected at least 24 observations (two periods). Got 23"
+	for name, res := range map[string]Result{
+		"Decompose":  Decompose(append([]float64(nil), X...), 12, 7, Additive()),
+		"Decomposer": NewDecomposer(12, 7, Additive()).Decompose(append([]float64(nil), X...)),
+		"batch":      DecomposeBatch([][]float64{append([]float64(nil), X...)}, 12, 7, Additive(), 1)[0],
+	} {
+		if res.Err == nil || res.Err.Error() != msg {
+			t.Errorf("%v: expected the error %q. Got %v", name, msg, res.Err)
+		}
+	}
+	if res := Decompose(append(X, 0), 12, 7, Additive()); res.Err != nil {
+		t.Errorf("Expected two periods to be decomposed. Got %v", res.Err)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chewxy/stl"
+	"github.com/pkg/errors"
+)
+
+// timeLayouts are the layouts of timestamps that are tried when no -time-format is given.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+}
+
+// table is the series read from the CSV.
+type table struct {
+	name   string // name of the column of values
+	values []float64
+
+	timeName string
+	stamps   []string // the timestamps as given
+	times    []time.Time
+}
+
+// readTable reads the column of values (and of timestamps, if timeColumn is not empty) from the CSV.
+func readTable(r io.Reader, column, timeColumn, timeFormat string, header bool) (*table, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the CSV")
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("The CSV is empty")
+	}
+	var names []string
+	if header {
+		names, rows = rows[0], rows[1:]
+	}
+
+	tab := &table{name: "data", timeName: "time"}
+	ti := -1
+	if timeColumn != "" {
+		if ti, err = columnIndex(timeColumn, names); err != nil {
+			return nil, errors.Wrap(err, "-time")
+		}
+	}
+	vi := 0
+	if ti == 0 {
+		vi = 1
+	}
+	if column != "" {
+		if vi, err = columnIndex(column, names); err != nil {
+			return nil, errors.Wrap(err, "-column")
+		}
+	}
+	if vi < len(names) {
+		tab.name = names[vi]
+	}
+	if ti >= 0 && ti < len(names) {
+		tab.timeName = names[ti]
+	}
+
+	line := 1
+	if header {
+		line = 2
+	}
+	for i, row := range rows {
+		if len(row) == 1 && row[0] == "" {
+			continue // blank line
+		}
+		if vi >= len(row) {
+			return nil, errors.Errorf("Line %d: expected at least %d columns. Got %d", line+i, vi+1, len(row))
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(row[vi]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Line %d", line+i)
+		}
+		tab.values = append(tab.values, v)
+
+		if ti < 0 {
+			continue
+		}
+		if ti >= len(row) {
+			return nil, errors.Errorf("Line %d: expected at least %d columns. Got %d", line+i, ti+1, len(row))
+		}
+		stamp := strings.TrimSpace(row[ti])
+		t, err := parseTime(stamp, timeFormat)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Line %d", line+i)
+		}
+		tab.stamps = append(tab.stamps, stamp)
+		tab.times = append(tab.times, t)
+	}
+	return tab, nil
+}
+
+// columnIndex finds a column by its name, or by its 0-based index.
+func columnIndex(column string, names []string) (int, error) {
+	for i, name := range names {
+		if strings.TrimSpace(name) == column {
+			return i, nil
+		}
+	}
+	i, err := strconv.Atoi(column)
+	if err != nil || i < 0 {
+		return 0, errors.Errorf("No column %q", column)
+	}
+	return i, nil
+}
+
+func parseTime(s, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, s)
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+	}
+	return time.Time{}, errors.Errorf("Unable to parse the timestamp %q. Use -time-format", s)
+}
+
+// inferSteps is the number of steps between the first timestamps from which the period is inferred.
+const inferSteps = 24
+
+// inferPeriod infers the periodicity of a regular series from the steps between its first timestamps. See stl.Series.
+//
+// Calendar steps are recognised although their durations vary: months of different lengths, month-end dates (2015-01-31, 2015-02-28, ...),
+// and days of the month that are clamped to the end of shorter months (2015-01-30, 2015-02-28, 2015-03-30, ...).
+func inferPeriod(times []time.Time) (int, error) {
+	if len(times) < 2 {
+		return 0, errors.New("Cannot infer the period from fewer than two timestamps")
+	}
+	if len(times) > inferSteps+1 {
+		times = times[:inferSteps+1]
+	}
+	if u, ok := calendarUnit(times); ok {
+		return stl.Series{Start: times[0], Unit: u}.Periodicity()
+	}
+	step := times[1].Sub(times[0])
+	for i := 2; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d != step {
+			return 0, errors.Errorf("Cannot infer the period of irregular timestamps: a step of %v follows a step of %v", d, step)
+		}
+	}
+	return stl.Series{Start: times[0], Step: step}.Periodicity()
+}
+
+// calendarUnit checks if the times are stepped by a calendar unit: the same number of days (a day or a week),
+// or the same number of months (a month, a quarter or a year) at the same day of the month, or at the end of the shorter months.
+// The times must all be at the same time of day.
+func calendarUnit(times []time.Time) (stl.Unit, bool) {
+	h, m, s := times[0].Clock()
+	anchor := 0 // the day of the month, before it is clamped to the end of shorter months
+	for _, t := range times {
+		if th, tm, ts := t.Clock(); th != h || tm != m || ts != s || t.Nanosecond() != times[0].Nanosecond() {
+			return stl.Fixed, false
+		}
+		if t.Day() > anchor {
+			anchor = t.Day()
+		}
+	}
+
+	days := civilDay(times[1]) - civilDay(times[0])
+	months := monthIndex(times[1]) - monthIndex(times[0])
+	for i := 1; i < len(times); i++ {
+		if civilDay(times[i])-civilDay(times[i-1]) != days {
+			days = 0
+		}
+		day := anchor
+		if n := daysIn(times[i]); n < day {
+			day = n
+		}
+		if monthIndex(times[i])-monthIndex(times[i-1]) != months || times[i].Day() != day {
+			months = 0
+		}
+	}
+	switch {
+	case days == 1:
+		return stl.Day, true
+	case days == 7:
+		return stl.Week, true
+	case months == 1:
+		return stl.Month, true
+	case months == 3:
+		return stl.Quarter, true
+	case months == 12:
+		return stl.Year, true
+	}
+	return stl.Fixed, false
+}
+
+// civilDay numbers the days of the calendar, ignoring the time of day and the time zone.
+func civilDay(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// monthIndex numbers the months of the calendar.
+func monthIndex(t time.Time) int { return t.Year()*12 + int(t.Month()) }
+
+// daysIn returns the number of days in the month of t.
+func daysIn(t time.Time) int { return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day() }
+
+// column is a named column of the output.
+type column struct {
+	name string
+	vals []float64
+}
+
+// write writes the components as CSV or JSON.
+func write(w io.Writer, format string, tab *table, res stl.Result) error {
+	columns := []column{
+		{tab.name, res.Data},
+		{"trend", res.Trend},
+		{"seasonal", res.Seasonal},
+		{"remainder", res.Resid},
+		{"weights", res.Weights},
+	}
+	if res.TrendSlope != nil {
+		columns = append(columns, column{"trend_slope", res.TrendSlope})
+	}
+
+	switch strings.ToLower(format) {
+	case "csv":
+		cw := csv.NewWriter(w)
+		var row []string
+		if tab.stamps != nil {
+			row = append(row, tab.timeName)
+		}
+		for _, c := range columns {
+			row = append(row, c.name)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+		for i := range res.Data {
+			row = row[:0]
+			if tab.stamps != nil {
+				row = append(row, tab.stamps[i])
+			}
+			for _, c := range columns {
+				row = append(row, strconv.FormatFloat(c.vals[i], 'g', -1, 64))
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		// the components are written as arrays, keyed by their names, in the order of the CSV columns
+		var b strings.Builder
+		b.WriteString("{")
+		if tab.stamps != nil {
+			k, _ := json.Marshal(tab.timeName)
+			stamps, err := json.Marshal(tab.stamps)
+			if err != nil {
+				return err
+			}
+			b.WriteString("\n  " + string(k) + ": " + string(stamps) + ",")
+		}
+		for j, c := range columns {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			k, _ := json.Marshal(c.name)
+			b.WriteString("\n  " + string(k) + ": ")
+			writeJSONFloats(&b, c.vals)
+		}
+		b.WriteString("\n}\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return errors.Errorf("Unknown format %q", format)
+}
+
+// writeJSONFloats writes the values as a JSON array. JSON has no NaN or infinities, so they are written as null.
+func writeJSONFloats(b *strings.Builder, vals []float64) {
+	b.WriteString("[")
+	for i, v := range vals {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			b.WriteString("null")
+			continue
+		}
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	b.WriteString("]")
+}
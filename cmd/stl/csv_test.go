@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chewxy/stl"
+)
+
+func dates(t *testing.T, stamps ...string) []time.Time {
+	retVal := make([]time.Time, len(stamps))
+	for i, s := range stamps {
+		var err error
+		if retVal[i], err = parseTime(s, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return retVal
+}
+
+func stepped(n int, f func(i int) time.Time) []time.Time {
+	retVal := make([]time.Time, n)
+	for i := range retVal {
+		retVal[i] = f(i)
+	}
+	return retVal
+}
+
+func TestInferPeriod(t *testing.T) {
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip(err)
+	}
+	cases := []struct {
+		name  string
+		times []time.Time
+		want  int
+	}{
+		{"monthly", stepped(36, func(i int) time.Time { return start.AddDate(0, i, 0) }), 12},
+		{"month end", dates(t, "2015-01-31", "2015-02-28", "2015-03-31", "2015-04-30", "2015-05-31", "2015-06-30"), 12},
+		{"clamped", dates(t, "2015-01-30", "2015-02-28", "2015-03-30", "2015-04-30", "2015-05-30"), 12},
+		{"leap year", dates(t, "2016-01-31", "2016-02-29", "2016-03-31"), 12},
+		{"quarter end", dates(t, "2015-03-31", "2015-06-30", "2015-09-30", "2015-12-31", "2016-03-31"), 4},
+		{"quarterly", stepped(12, func(i int) time.Time { return start.AddDate(0, 3*i, 0) }), 4},
+		{"daily", stepped(30, func(i int) time.Time { return start.AddDate(0, 0, i) }), 7},
+		{"daily across daylight saving", stepped(30, func(i int) time.Time { return time.Date(2015, 3, 20+i, 9, 0, 0, 0, sydney) }), 7},
+		{"weekly", stepped(30, func(i int) time.Time { return start.AddDate(0, 0, 7*i) }), 52},
+		{"hourly", stepped(48, func(i int) time.Time { return start.Add(time.Duration(i) * time.Hour) }), 24},
+	}
+	for _, c := range cases {
+		got, err := inferPeriod(c.times)
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%v: expected a period of %d. Got %d", c.name, c.want, got)
+		}
+	}
+
+	bad := []struct {
+		name  string
+		times []time.Time
+		err   string
+	}{
+		{"single", dates(t, "2015-01-01"), "fewer than two"},
+		{"irregular", dates(t, "2015-01-01", "2015-01-02", "2015-01-04"), "irregular"},
+		{"drifting day", dates(t, "2015-01-31", "2015-02-28", "2015-03-29"), "irregular"},
+		{"yearly", stepped(5, func(i int) time.Time { return start.AddDate(i, 0, 0) }), "Year"},
+	}
+	for _, c := range bad {
+		_, err := inferPeriod(c.times)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v: expected an error containing %q. Got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	tab := &table{name: "value", timeName: "day", stamps: []string{"2015-01-01", "2015-01-02", "2015-01-03"}}
+	res := stl.Result{
+		Data:     []float64{1, 0, 2},
+		Trend:    []float64{1, math.NaN(), 2},
+		Seasonal: []float64{0, math.Inf(1), 1e-300},
+		Resid:    []float64{0, math.Inf(-1), -0.5},
+		Weights:  []float64{1, 1, 1},
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, "json", tab, res); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	var days []string
+	if err := json.Unmarshal(got["day"], &days); err != nil || len(days) != 3 {
+		t.Fatalf("Unexpected timestamps %s: %v", got["day"], err)
+	}
+	for name, expected := range map[string][]float64{"value": res.Data, "trend": res.Trend, "seasonal": res.Seasonal, "remainder": res.Resid, "weights": res.Weights} {
+		var vals []*float64
+		if err := json.Unmarshal(got[name], &vals); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if len(vals) != len(expected) {
+			t.Fatalf("%v: expected %d values. Got %d", name, len(expected), len(vals))
+		}
+		for i, v := range expected {
+			switch {
+			case math.IsNaN(v) || math.IsInf(v, 0):
+				if vals[i] != nil {
+					t.Errorf("%v[%d]: expected null. Got %v", name, i, *vals[i])
+				}
+			case vals[i] == nil || *vals[i] != v:
+				t.Errorf("%v[%d]: expected %v. Got %v", name, i, v, vals[i])
+			}
+		}
+	}
+	if _, ok := got["trend_slope"]; ok {
+		t.Error("Expected no trend_slope without WithTrendSlope")
+	}
+}
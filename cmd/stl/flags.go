@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/chewxy/stl"
+	"github.com/chewxy/stl/loess"
+	"github.com/pkg/errors"
+)
+
+// command is the parsed command line.
+type command struct {
+	file                     string
+	column, timeColumn       string
+	timeFormat               string
+	header                   bool
+	format                   string
+	chartWidth, chartHeight  int
+	imageWidth, imageHeight  int
+	threshold                float64
+	period                   float64
+	width                    int
+	model                    string
+	lambda                   float64
+	robust, iter             int
+	fourier                  int
+	criterion                string
+	trendSlope               bool
+	parallel                 int
+	seasonal, trend, lowpass smoother
+	set                      map[string]bool // the flags that were given
+}
+
+// smoother holds the flags that configure one of the LOESS smoothers. See stl.Config.
+type smoother struct {
+	name          string
+	width, jump   int
+	degree        int
+	kernel        string
+	fast          bool
+	interpolation string
+}
+
+func (s *smoother) register(fs *flag.FlagSet) {
+	fs.IntVar(&s.width, s.name+"-width", 0, "width of the "+s.name+" smoother, in points")
+	fs.IntVar(&s.jump, s.name+"-jump", 0, "number of points to skip between evaluations of the "+s.name+" smoother")
+	fs.IntVar(&s.degree, s.name+"-degree", 1, "degree of the local polynomials of the "+s.name+" smoother: 0, 1 or 2")
+	fs.StringVar(&s.kernel, s.name+"-kernel", "tricube", "kernel of the "+s.name+" smoother: tricube, epanechnikov, gaussian, biweight or uniform")
+	fs.BoolVar(&s.fast, s.name+"-fast", false, "use the fast (FFT) path of the "+s.name+" smoother")
+	fs.StringVar(&s.interpolation, s.name+"-interpolation", "linear", "interpolation between the jumps of the "+s.name+" smoother: linear or hermite")
+}
+
+// config applies the flags of the smoother that were given to the default configuration.
+func (s *smoother) config(def stl.Config, set map[string]bool) (conf stl.Config, changed bool, err error) {
+	conf = def
+	for name := range set {
+		changed = changed || strings.HasPrefix(name, s.name+"-")
+	}
+	if set[s.name+"-width"] {
+		conf.Width = s.width
+	}
+	if set[s.name+"-jump"] {
+		conf.Jump = s.jump
+	}
+	if set[s.name+"-degree"] {
+		switch s.degree {
+		case 0:
+			conf.Fn = loess.Constant
+		case 1:
+			conf.Fn = loess.Linear
+		case 2:
+			conf.Fn = loess.Quadratic
+		default:
+			return conf, changed, errors.Errorf("-%s-degree must be 0, 1 or 2. Got %d", s.name, s.degree)
+		}
+	}
+	if conf.Kernel, err = parseKernel(s.kernel); err != nil {
+		return conf, changed, errors.Wrapf(err, "-%s-kernel", s.name)
+	}
+	conf.Fast = s.fast
+	if conf.Interpolation, err = parseInterpolation(s.interpolation); err != nil {
+		return conf, changed, errors.Wrapf(err, "-%s-interpolation", s.name)
+	}
+	return conf, changed, nil
+}
+
+func parseKernel(name string) (loess.Kernel, error) {
+	for k := loess.Tricube; k <= loess.Uniform; k++ {
+		if strings.EqualFold(name, k.String()) {
+			return k, nil
+		}
+	}
+	return 0, errors.Errorf("Unknown kernel %q", name)
+}
+
+func parseInterpolation(name string) (loess.Interpolation, error) {
+	for m := loess.LinearInterpolation; m <= loess.HermiteInterpolation; m++ {
+		if strings.EqualFold(name, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, errors.Errorf("Unknown interpolation %q", name)
+}
+
+// parse parses the command line. Usage and flag errors are written to stderr.
+func parse(args []string, stderr io.Writer) (*command, error) {
+	c := &command{
+		seasonal: smoother{name: "seasonal"},
+		trend:    smoother{name: "trend"},
+		lowpass:  smoother{name: "lowpass"},
+	}
+	fs := flag.NewFlagSet("stl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stl [flags] [file]\n\nDecomposes a series read from a CSV file, or stdin if no file is given.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&c.column, "column", "", "name (with -header) or 0-based index of the column of values. The default is the first column that is not the -time column")
+	fs.StringVar(&c.timeColumn, "time", "", "name (with -header) or 0-based index of the column of timestamps, if any")
+	fs.StringVar(&c.timeFormat, "time-format", "", "layout of the timestamps, as in Go's time package. The default accepts RFC 3339, dates, date-times and Unix seconds")
+	fs.BoolVar(&c.header, "header", true, "the first row of the CSV is a header")
+	fs.StringVar(&c.format, "format", "csv", "output format: csv, json, text (a chart of the components), svg or png")
+	fs.IntVar(&c.chartWidth, "chart-width", 80, "width of the text chart, in characters")
+	fs.IntVar(&c.chartHeight, "chart-height", 10, "height of each panel of the text chart, in lines")
+	fs.IntVar(&c.imageWidth, "image-width", 800, "width of the svg or png image, in pixels")
+	fs.IntVar(&c.imageHeight, "image-height", 600, "height of the svg or png image, in pixels")
+	fs.Float64Var(&c.threshold, "outlier-threshold", 0.5, "robustness weight below which an observation is marked as an outlier in the svg or png image. 0 marks none")
+
+	fs.Float64Var(&c.period, "period", 0, "number of observations per seasonal cycle. A non-integer period performs a fractional decomposition. The default is inferred from the timestamps")
+	fs.IntVar(&c.width, "width", 7, "width of the seasonal smoother, in cycles")
+	fs.StringVar(&c.model, "model", "additive", "model: additive, multiplicative or boxcox (see -lambda)")
+	fs.Float64Var(&c.lambda, "lambda", 0.5, "lambda of the boxcox model (see stl.UnsafeTransform)")
+
+	fs.IntVar(&c.robust, "robust", 0, "number of robust (outlier downweighting) iterations")
+	fs.IntVar(&c.iter, "iter", 2, "number of inner iterations")
+	fs.IntVar(&c.fourier, "fourier", -1, "estimate the seasonal component with this many Fourier harmonics. 0 selects the number automatically. Negative smooths the cycle-subseries")
+	fs.StringVar(&c.criterion, "fourier-criterion", "aic", "criterion by which the number of harmonics is selected: aic or cv")
+	fs.BoolVar(&c.trendSlope, "trend-slope", false, "also output the local slope of the trend")
+	fs.IntVar(&c.parallel, "parallel", 0, "number of goroutines to smooth the cycle-subseries with")
+
+	c.seasonal.register(fs)
+	c.trend.register(fs)
+	c.lowpass.register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		c.file = fs.Arg(0)
+	default:
+		return nil, errors.Errorf("Expected at most one file. Got %d", fs.NArg())
+	}
+	c.set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { c.set[f.Name] = true })
+	return c, nil
+}
+
+// modelType returns the model of the decomposition.
+func (c *command) modelType() (stl.ModelType, error) {
+	switch strings.ToLower(c.model) {
+	case "additive":
+		return stl.Additive(), nil
+	case "multiplicative":
+		return stl.Multiplicative(), nil
+	case "boxcox":
+		if c.lambda < 0 {
+			return stl.ModelType{}, errors.Errorf("-lambda cannot be less than 0. Got %v", c.lambda)
+		}
+		return stl.UnsafeTransform(c.lambda), nil
+	}
+	return stl.ModelType{}, errors.Errorf("Unknown model %q", c.model)
+}
+
+// opts returns the options of the decomposition of a series with the given periodicity (rounded up, for a fractional period).
+func (c *command) opts(periodicity int) ([]stl.Opt, error) {
+	opts := []stl.Opt{stl.WithRobustIter(c.robust), stl.WithIter(c.iter)}
+	if c.fourier >= 0 {
+		opts = append(opts, stl.WithFourier(c.fourier))
+		switch strings.ToLower(c.criterion) {
+		case "aic":
+			opts = append(opts, stl.WithFourierCriterion(stl.AIC))
+		case "cv":
+			opts = append(opts, stl.WithFourierCriterion(stl.CV))
+		default:
+			return nil, errors.Errorf("Unknown Fourier criterion %q", c.criterion)
+		}
+	}
+	if c.trendSlope {
+		opts = append(opts, stl.WithTrendSlope())
+	}
+	if c.parallel > 1 {
+		opts = append(opts, stl.WithParallel(c.parallel))
+	}
+	if c.width < 1 {
+		return nil, errors.Errorf("-width must be positive. Got %d", c.width)
+	}
+
+	smoothers := []struct {
+		s    *smoother
+		def  stl.Config
+		with func(stl.Config) stl.Opt
+	}{
+		{&c.seasonal, stl.DefaultSeasonal(c.width), stl.WithSeasonalConfig},
+		{&c.trend, stl.DefaultTrend(periodicity, c.width), stl.WithTrendConfig},
+		{&c.lowpass, stl.DefaultLowPass(periodicity), stl.WithLowpassConfig},
+	}
+	for _, sm := range smoothers {
+		conf, changed, err := sm.s.config(sm.def, c.set)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			opts = append(opts, sm.with(conf))
+		}
+	}
+	return opts, nil
+}
+
+// decompose decomposes the values of the table.
+func (c *command) decompose(tab *table) (stl.Result, error) {
+	period := c.period
+	if period == 0 {
+		if tab.times == nil {
+			return stl.Result{}, errors.New("Cannot infer the period without a -time column. Use -period")
+		}
+		p, err := inferPeriod(tab.times)
+		if err != nil {
+			return stl.Result{}, errors.Wrap(err, "Use -period")
+		}
+		period = float64(p)
+	}
+	if period < 2 {
+		return stl.Result{}, errors.Errorf("-period must be at least 2. Got %v", period)
+	}
+
+	m, err := c.modelType()
+	if err != nil {
+		return stl.Result{}, err
+	}
+	opts, err := c.opts(int(math.Ceil(period)))
+	if err != nil {
+		return stl.Result{}, err
+	}
+
+	// the decomposition transforms the data in place
+	X := append([]float64(nil), tab.values...)
+	var res stl.Result
+	if period == math.Trunc(period) {
+		res = stl.Decompose(X, int(period), c.width, m, opts...)
+	} else {
+		res = stl.DecomposeFractional(X, period, c.width, m, opts...)
+	}
+	return res, res.Err
+}
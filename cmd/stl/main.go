@@ -0,0 +1,78 @@
+// Command stl performs a STL decomposition of a series read from a CSV file (or stdin),
+// and writes the trend, seasonal and remainder components, as well as the robustness weights, to stdout as CSV or JSON.
+// They can also be drawn as a text chart, with -format text, or as an image, with -format svg or png.
+//
+// Usage:
+//
+//	stl [flags] [file]
+//
+// For example, to decompose the monthly CO2 data in the repository with two robust iterations:
+//
+//	stl -period 12 -width 35 -robust 2 testdata/co2.csv
+//
+// If the period is not given, it is inferred from the steps between the timestamps of the -time column (e.g. 12 for monthly data).
+// A non-integer period (e.g. 52.18 for weekly data) performs a fractional decomposition.
+// The smoothers of the seasonal, trend and lowpass components can be configured with the -seasonal-*, -trend-* and -lowpass-* flags.
+// Those that are not given keep their defaults.
+//
+// JSON has no NaN or infinite values (which a multiplicative model of data with zeros may produce), so they are written as null.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chewxy/stl/plot"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == flag.ErrHelp:
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "stl:", err)
+		os.Exit(2)
+	}
+}
+
+// run runs the command with the arguments, reading from stdin if no file is given.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd, err := parse(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	in := stdin
+	if cmd.file != "" && cmd.file != "-" {
+		f, err := os.Open(cmd.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	tab, err := readTable(in, cmd.column, cmd.timeColumn, cmd.timeFormat, cmd.header)
+	if err != nil {
+		return err
+	}
+	res, err := cmd.decompose(tab)
+	if err != nil {
+		return err
+	}
+	opts := []plot.Opt{plot.WithOutlierThreshold(cmd.threshold)}
+	if tab.times != nil {
+		opts = append(opts, plot.WithTimes(tab.times))
+	}
+	switch strings.ToLower(cmd.format) {
+	case "text":
+		return plot.Text(stdout, res, cmd.chartWidth, cmd.chartHeight, opts...)
+	case "svg":
+		return plot.SVG(stdout, res, cmd.imageWidth, cmd.imageHeight, opts...)
+	case "png":
+		return plot.PNG(stdout, res, cmd.imageWidth, cmd.imageHeight, opts...)
+	}
+	return write(stdout, cmd.format, tab, res)
+}
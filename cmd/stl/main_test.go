@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chewxy/stl"
+	"github.com/chewxy/stl/loess"
+)
+
+const co2 = "../../testdata/co2.csv"
+
+func loadCO2(t *testing.T) []float64 {
+	f, err := os.Open(co2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tab, err := readTable(f, "", "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tab.values
+}
+
+func runOK(t *testing.T, stdin io.Reader, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if err := run(args, stdin, &stdout, &stderr); err != nil {
+		t.Fatalf("%v: %v\n%s", args, err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestRunCSV(t *testing.T) {
+	out := runOK(t, nil, "-period", "12", "-width", "35", "-robust", "2", co2)
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rows[0], ","); got != "CO2Levels,trend,seasonal,remainder,weights" {
+		t.Fatalf("Unexpected header %q", got)
+	}
+
+	data := loadCO2(t)
+	res := stl.Decompose(data, 12, 35, stl.Additive(), stl.WithRobustIter(2), stl.WithIter(2))
+	if len(rows) != len(data)+1 {
+		t.Fatalf("Expected %d rows. Got %d", len(data)+1, len(rows))
+	}
+	for i, row := range rows[1:] {
+		for j, expected := range []float64{res.Data[i], res.Trend[i], res.Seasonal[i], res.Resid[i], res.Weights[i]} {
+			got, err := strconv.ParseFloat(row[j], 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != expected {
+				t.Fatalf("Row %d, column %v: expected %v. Got %v", i, rows[0][j], expected, got)
+			}
+		}
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	// a monthly series with timestamps, from which the period is inferred
+	data := loadCO2(t)
+	var in strings.Builder
+	in.WriteString("month,value\n")
+	start := time.Date(1959, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range data {
+		fmt.Fprintf(&in, "%s,%v\n", start.AddDate(0, i, 0).Format("2006-01"), v)
+	}
+
+	out := runOK(t, strings.NewReader(in.String()), "-time", "month", "-width", "35", "-format", "json", "-trend-slope", "-seasonal-kernel", "Gaussian", "-trend-width", "25")
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	var months []string
+	if err := json.Unmarshal(got["month"], &months); err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != len(data) || months[1] != "1959-02" {
+		t.Fatalf("Unexpected timestamps %v", months[:2])
+	}
+
+	conf := stl.DefaultSeasonal(35)
+	conf.Kernel = loess.Gaussian
+	tconf := stl.DefaultTrend(12, 35)
+	tconf.Width = 25
+	res := stl.Decompose(append([]float64(nil), data...), 12, 35, stl.Additive(), stl.WithSeasonalConfig(conf), stl.WithTrendConfig(tconf), stl.WithTrendSlope())
+	for name, expected := range map[string][]float64{"value": res.Data, "trend": res.Trend, "seasonal": res.Seasonal, "remainder": res.Resid, "weights": res.Weights, "trend_slope": res.TrendSlope} {
+		var vals []float64
+		if err := json.Unmarshal(got[name], &vals); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		for i := range expected {
+			if vals[i] != expected[i] {
+				t.Fatalf("%v[%d]: expected %v. Got %v", name, i, expected[i], vals[i])
+			}
+		}
+	}
+}
+
+func TestRunFractional(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 520; i++ {
+		fmt.Fprintf(&in, "%v\n", 10+math.Sin(2*math.Pi*float64(i)/52.18))
+	}
+	out := runOK(t, strings.NewReader(in.String()), "-header=false", "-period", "52.18", "-width", "7")
+	if n := strings.Count(out, "\n"); n != 521 {
+		t.Errorf("Expected 521 lines. Got %d", n)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		args  []string
+		stdin string
+	}{
+		{[]string{"-period", "12"}, ""},
+		{[]string{"-period", "12"}, "x\n1\n2\nfoo\n"},
+		{[]string{"-width", "35"}, "x\n1\n2\n3\n"},
+		{[]string{"-period", "2", "-model", "foo"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-period", "2", "-seasonal-kernel", "foo"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-period", "2", "-trend-degree", "3"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-period", "2", "-format", "xml"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-period", "2", "-column", "y"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-period", "12"}, "x\n1\n2\n3\n4\n"},
+		{[]string{"-nope"}, ""},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if err := run(c.args, strings.NewReader(c.stdin), &stdout, &stderr); err == nil {
+			t.Errorf("%v with %q: expected an error", c.args, c.stdin)
+		}
+	}
+}
+
+func TestRunText(t *testing.T) {
+	out := runOK(t, nil, "-period", "12", "-width", "35", "-format", "text", "-chart-width", "60", "-chart-height", "5", co2)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 4*6+2 || lines[0] != "Data" {
+		t.Fatalf("Unexpected chart:\n%s", out)
+	}
+}
+
+func TestRunImages(t *testing.T) {
+	out := runOK(t, nil, "-period", "12", "-width", "35", "-robust", "2", "-format", "svg", co2)
+	if !strings.HasPrefix(out, "<svg") || !strings.HasSuffix(out, "</svg>\n") {
+		t.Errorf("Expected an SVG image. Got %.40q", out)
+	}
+	out = runOK(t, nil, "-period", "12", "-width", "35", "-format", "png", "-image-width", "300", "-image-height", "200", co2)
+	img, err := png.Decode(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() != 200 {
+		t.Errorf("Expected a 300x200 image. Got %v", b)
+	}
+}
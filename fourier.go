@@ -0,0 +1,287 @@
+package stl
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// FourierCriterion is the criterion used to select the number of harmonics of a Fourier seasonal component.
+type FourierCriterion int
+
+const (
+	// AIC selects the number of harmonics with the lowest Akaike Information Criterion.
+	AIC FourierCriterion = iota
+	// CV selects the number of harmonics with the lowest leave-one-out cross validation error.
+	CV
+)
+
+// maxAutoHarmonics is the largest number of harmonics considered when the number of harmonics is automatically selected.
+const maxAutoHarmonics = 20
+
+// fourierState estimates the seasonal component as a sum of K harmonics of the period:
+//
+//	S(t) = Σ_k a_k cos(2πkt/P) + b_k sin(2πkt/P)
+//
+// fitted by weighted least squares. An intercept is fitted alongside the harmonics (and discarded), so the seasonal component has zero mean.
+type fourierState struct {
+	k    int // requested number of harmonics. <= 0 means automatic selection
+	crit FourierCriterion
+
+	period float64
+	basis  [][]float64 // basis[c] is the cth column of the design matrix (cos 1, sin 1, cos 2, ...), excluding the intercept
+	chosen int         // number of harmonics used in the last fit
+}
+
+// maxHarmonics returns the largest number of harmonics that can be fitted with the given period.
+func (s *fourierState) maxHarmonics(n int) int {
+	max := int(s.period / 2)
+	if s.k > 0 && s.k < max {
+		max = s.k
+	}
+	if s.k <= 0 && max > maxAutoHarmonics {
+		max = maxAutoHarmonics
+	}
+	// leave enough degrees of freedom for the fit
+	for max > 0 && 2*max+1 >= n {
+		max--
+	}
+	return max
+}
+
+// setupBasis computes the design matrix of the harmonics for n observations.
+func (s *fourierState) setupBasis(n int, period float64) {
+	s.period = period
+	kmax := s.maxHarmonics(n)
+	s.basis = s.basis[:0]
+	for k := 1; k <= kmax; k++ {
+		c := make([]float64, n)
+		sn := make([]float64, n)
+		for i := range c {
+			theta := 2 * math.Pi * float64(k) * float64(i) / period
+			c[i] = math.Cos(theta)
+			sn[i] = math.Sin(theta)
+		}
+		s.basis = append(s.basis, c)
+
+		// at the Nyquist frequency of an even period, the sine column is identically 0.
+		if float64(2*k) != period {
+			s.basis = append(s.basis, sn)
+		}
+	}
+}
+
+// columns returns the number of basis columns used by k harmonics.
+func (s *fourierState) columns(k int) int {
+	c := 2 * k
+	if float64(2*k) == s.period {
+		c--
+	}
+	if c > len(s.basis) {
+		c = len(s.basis)
+	}
+	return c
+}
+
+// fit fits the harmonics to X, writing the seasonal component into retVal.
+func (s *fourierState) fit(X, weights, retVal []float64) error {
+	if len(s.basis) == 0 {
+		return errors.Errorf("Not enough observations (%d) to fit harmonics of period %v", len(X), s.period)
+	}
+
+	k := s.k
+	if k <= 0 {
+		best := math.Inf(1)
+		for cand := 1; 2*cand-1 <= len(s.basis); cand++ {
+			score, err := s.score(X, weights, cand)
+			if err != nil {
+				continue
+			}
+			if score < best {
+				best = score
+				k = cand
+			}
+		}
+		if k <= 0 {
+			return errors.New("Unable to select the number of harmonics")
+		}
+	}
+
+	cols := s.columns(k)
+	beta, _, err := s.solve(X, weights, cols)
+	if err != nil {
+		return err
+	}
+	for i := range retVal {
+		var v float64
+		for c := 0; c < cols; c++ {
+			v += beta[c+1] * s.basis[c][i]
+		}
+		retVal[i] = v
+	}
+	s.chosen = k
+	return nil
+}
+
+// row returns the ith row of the design matrix with the first cols basis columns (plus the intercept)
+func (s *fourierState) row(i, cols int, r []float64) {
+	r[0] = 1
+	for c := 0; c < cols; c++ {
+		r[c+1] = s.basis[c][i]
+	}
+}
+
+// solve solves the weighted least squares problem for the first cols basis columns.
+// It returns the coefficients (intercept first), and the Cholesky factor of the normal equations.
+func (s *fourierState) solve(X, weights []float64, cols int) (beta []float64, chol []float64, err error) {
+	p := cols + 1
+	a := make([]float64, p*p)
+	b := make([]float64, p)
+	r := make([]float64, p)
+	for i := range X {
+		w := 1.0
+		if len(weights) > 0 {
+			w = weights[i]
+		}
+		if w == 0 {
+			continue
+		}
+		s.row(i, cols, r)
+		for j := 0; j < p; j++ {
+			wr := w * r[j]
+			b[j] += wr * X[i]
+			for k := 0; k <= j; k++ {
+				a[j*p+k] += wr * r[k]
+			}
+		}
+	}
+	if err = cholesky(a, p); err != nil {
+		return nil, nil, err
+	}
+	return cholSolve(a, p, b), a, nil
+}
+
+// score computes the selection criterion of using k harmonics.
+func (s *fourierState) score(X, weights []float64, k int) (float64, error) {
+	cols := s.columns(k)
+	p := cols + 1
+	beta, chol, err := s.solve(X, weights, cols)
+	if err != nil {
+		return 0, err
+	}
+
+	var rss, cv, sumw float64
+	r := make([]float64, p)
+	for i := range X {
+		w := 1.0
+		if len(weights) > 0 {
+			w = weights[i]
+		}
+		if w == 0 {
+			continue
+		}
+		s.row(i, cols, r)
+		var fitted float64
+		for j := range r {
+			fitted += beta[j] * r[j]
+		}
+		res := X[i] - fitted
+		rss += w * res * res
+		sumw += w
+
+		if s.crit == CV {
+			// leverage: w x_i' (X'WX)^-1 x_i
+			forwardSubst(chol, p, r)
+			var h float64
+			for j := range r {
+				h += r[j] * r[j]
+			}
+			h *= w
+			if h >= 1 {
+				return math.Inf(1), nil
+			}
+			d := res / (1 - h)
+			cv += w * d * d
+		}
+	}
+	if sumw <= float64(p) {
+		return 0, errors.New("Not enough weighted observations")
+	}
+
+	switch s.crit {
+	case CV:
+		return cv / sumw, nil
+	default:
+		return sumw*math.Log(rss/sumw) + 2*float64(p), nil
+	}
+}
+
+// cholesky performs an in-place Cholesky decomposition of the lower triangle of the p×p matrix a.
+func cholesky(a []float64, p int) error {
+	for j := 0; j < p; j++ {
+		d := a[j*p+j]
+		for k := 0; k < j; k++ {
+			d -= a[j*p+k] * a[j*p+k]
+		}
+		if d <= 1e-12 {
+			return errors.New("Matrix is not positive definite")
+		}
+		d = math.Sqrt(d)
+		a[j*p+j] = d
+		for i := j + 1; i < p; i++ {
+			v := a[i*p+j]
+			for k := 0; k < j; k++ {
+				v -= a[i*p+k] * a[j*p+k]
+			}
+			a[i*p+j] = v / d
+		}
+	}
+	return nil
+}
+
+// forwardSubst solves Ly = b in place, where L is the Cholesky factor.
+func forwardSubst(l []float64, p int, b []float64) {
+	for i := 0; i < p; i++ {
+		v := b[i]
+		for k := 0; k < i; k++ {
+			v -= l[i*p+k] * b[k]
+		}
+		b[i] = v / l[i*p+i]
+	}
+}
+
+// cholSolve solves LL'x = b, where L is the Cholesky factor.
+func cholSolve(l []float64, p int, b []float64) []float64 {
+	forwardSubst(l, p, b)
+	for i := p - 1; i >= 0; i-- {
+		v := b[i]
+		for k := i + 1; k < p; k++ {
+			v -= l[k*p+i] * b[k]
+		}
+		b[i] = v / l[i*p+i]
+	}
+	return b
+}
+
+// updateHarmonics replaces the subcycle smoothing and lowpass filtering steps of the inner loop
+// when the seasonal component is estimated with harmonics.
+func (s *state) updateHarmonics(useWeights bool) (err error) {
+	var weights []float64
+	if useWeights {
+		weights = s.weights
+	}
+	if s.fourier.basis == nil {
+		period := s.period
+		if period == 0 {
+			period = float64(s.periodicity)
+		}
+		s.fourier.setupBasis(len(s.Data), period)
+	}
+	if err = s.fourier.fit(s.detrend, weights, s.Seasonal); err != nil {
+		return err
+	}
+	for i := range s.Seasonal {
+		s.detrend[i] = s.Data[i] - s.Seasonal[i]
+	}
+	return s.smoothTrend()
+}
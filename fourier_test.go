@@ -0,0 +1,50 @@
+package stl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDecomposeFourier(t *testing.T) {
+	// four years of daily data, with a yearly seasonality made of 3 harmonics
+	const period = 365
+	r := rand.New(rand.NewSource(1337))
+	n := 4 * period
+	data := make([]float64, n)
+	seasonal := make([]float64, n)
+	for i := range data {
+		theta := 2 * math.Pi * float64(i) / period
+		seasonal[i] = 10*math.Sin(theta) + 4*math.Cos(2*theta) + 2*math.Sin(3*theta)
+		data[i] = 50 + 0.01*float64(i) + seasonal[i] + r.NormFloat64()
+	}
+
+	for _, crit := range []FourierCriterion{AIC, CV} {
+		X := make([]float64, n)
+		copy(X, data)
+		s := newState(X, period, 7, WithFourier(0), WithFourierCriterion(crit))
+		if err := s.iterate(); err != nil {
+			t.Fatal(err)
+		}
+		if s.fourier.chosen < 3 || s.fourier.chosen > 6 {
+			t.Errorf("Criterion %v: expected about 3 harmonics to be chosen. Got %d", crit, s.fourier.chosen)
+		}
+
+		var sse float64
+		for i := range seasonal {
+			d := s.Seasonal[i] - seasonal[i]
+			sse += d * d
+		}
+		if rmse := math.Sqrt(sse / float64(n)); rmse > 0.5 {
+			t.Errorf("Criterion %v: seasonal component RMSE too large: %v", crit, rmse)
+		}
+	}
+
+	// fixed number of harmonics, with a fractional period
+	X := make([]float64, n)
+	copy(X, data)
+	res := DecomposeFractional(X, 365.25, 7, Additive(), WithFourier(3), WithRobustIter(1))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+}
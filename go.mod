@@ -1,12 +1,27 @@
 module github.com/chewxy/stl
 
-go 1.13
+go 1.18
 
 require (
 	github.com/chewxy/tightywhities v1.0.0
 	github.com/pkg/errors v0.9.1
-	golang.org/x/exp v0.0.0-20191030013958-a1ab85dbe136 // indirect
-	gonum.org/v1/gonum v0.12.0 // indirect
 	gorgonia.org/dawson v1.2.0
 	gorgonia.org/tensor v0.9.24
 )
+
+require (
+	github.com/apache/arrow/go/arrow v0.0.0-20201229220542-30ce2eb5d4dc // indirect
+	github.com/chewxy/hm v1.0.0 // indirect
+	github.com/chewxy/math32 v1.0.8 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/protobuf v1.4.3 // indirect
+	github.com/google/flatbuffers v1.12.0 // indirect
+	github.com/xtgo/set v1.0.0 // indirect
+	go4.org/unsafe/assume-no-moving-gc v0.0.0-20220617031537-928513b29760 // indirect
+	golang.org/x/exp v0.0.0-20191030013958-a1ab85dbe136 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	gonum.org/v1/gonum v0.12.0 // indirect
+	google.golang.org/protobuf v1.25.0 // indirect
+	gorgonia.org/vecf32 v0.9.0 // indirect
+	gorgonia.org/vecf64 v0.9.0 // indirect
+)
@@ -0,0 +1,132 @@
+package loess
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Criterion is a criterion by which the width of a smoother is selected. For all criteria, lower is better.
+type Criterion int
+
+const (
+	// GCV is the generalized cross validation score: n·RSS / (n - ENP)²
+	GCV Criterion = iota
+	// AICc is the corrected Akaike Information Criterion of Hurvich, Simonoff and Tsai (1998): log(RSS/n) + 1 + 2(ENP+1)/(n-ENP-2)
+	AICc
+	// LOOCV is the leave-one-out cross validation score: the mean of (r_i / (1 - L_ii))², where L_ii is the leverage of the ith point.
+	LOOCV
+)
+
+func (c Criterion) String() string {
+	switch c {
+	case GCV:
+		return "GCV"
+	case AICc:
+		return "AICc"
+	case LOOCV:
+		return "LOOCV"
+	}
+	return fmt.Sprintf("Criterion(%d)", int(c))
+}
+
+// WidthScore is the score of a candidate width.
+type WidthScore struct {
+	Width int
+	ENP   float64 // equivalent number of parameters
+	Score float64
+}
+
+// SelectWidth selects the width for smoothing x (with jump == 1) that minimizes the criterion.
+// The criteria only need the diagonal of the operator matrix, which is computed alongside the fitted values.
+//
+// If no candidate widths are provided, up to 50 odd widths from 5 to len(x) (or len(x)-1 if it is even) are tried.
+// An unknown criterion is an error.
+// The score of every candidate is returned as the criterion curve, in the order of the candidates.
+func SelectWidth(x []float64, widths []int, fn WeightUpdate, crit Criterion) (best int, curve []WidthScore, err error) {
+	n := len(x)
+	if n < 3 {
+		return 0, nil, errors.Errorf("Cannot select a width for %d points", n)
+	}
+	if crit < GCV || crit > LOOCV {
+		return 0, nil, errors.Errorf("Unknown criterion %v", crit)
+	}
+	if len(widths) == 0 {
+		widths = defaultWidths(n)
+	}
+
+	bestScore := math.Inf(1)
+	for _, w := range widths {
+		if w < 1 {
+			return 0, nil, errors.Errorf("Invalid width %d", w)
+		}
+		f, rss, err := New(w, x).fit(fn)
+		if err != nil {
+			return 0, nil, errors.Wrapf(err, "Failed to fit width %d", w)
+		}
+		score, err := f.score(rss, crit)
+		if err != nil {
+			return 0, nil, err
+		}
+		sc := WidthScore{Width: w, ENP: f.ENP, Score: score}
+		curve = append(curve, sc)
+		if sc.Score < bestScore {
+			bestScore = sc.Score
+			best = w
+		}
+	}
+	if math.IsInf(bestScore, 1) {
+		return 0, curve, errors.New("No candidate width could be scored")
+	}
+	return best, curve, nil
+}
+
+// score computes the criterion of a fit.
+func (f *Fit) score(rss float64, crit Criterion) (float64, error) {
+	n := float64(len(f.Fitted))
+	switch crit {
+	case GCV:
+		d := n - f.ENP
+		if d <= 0 {
+			return math.Inf(1), nil
+		}
+		return n * rss / (d * d), nil
+	case AICc:
+		d := n - f.ENP - 2
+		if d <= 0 || rss <= 0 {
+			return math.Inf(1), nil
+		}
+		return math.Log(rss/n) + 1 + 2*(f.ENP+1)/d, nil
+	case LOOCV:
+		var cv float64
+		for i, r := range f.Residuals {
+			d := 1 - f.Leverage[i]
+			if d <= 1e-12 {
+				return math.Inf(1), nil
+			}
+			r /= d
+			cv += r * r
+		}
+		return cv / n, nil
+	}
+	return 0, errors.Errorf("Unknown criterion %v", crit)
+}
+
+// maxDefaultWidths is the largest number of candidate widths tried by SelectWidth.
+const maxDefaultWidths = 50
+
+// defaultWidths returns up to 50 odd widths between 5 and n (inclusive), evenly spaced. The largest is n, or n-1 if n is even.
+func defaultWidths(n int) []int {
+	largest := n - 1 + n%2
+	if largest <= 5 {
+		return []int{largest}
+	}
+	count := (largest-5)/2 + 1 // the odd widths from 5 to largest
+	step := 2 * ((count - 1 + maxDefaultWidths - 2) / (maxDefaultWidths - 1))
+	var retVal []int
+	for w := 5; w < largest; w += step {
+		retVal = append(retVal, w)
+	}
+	return append(retVal, largest)
+}
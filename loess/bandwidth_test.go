@@ -0,0 +1,71 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSelectWidth(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	n := 200
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = math.Sin(float64(i)/20) + 0.3*r.NormFloat64()
+	}
+
+	for _, crit := range []Criterion{GCV, AICc, LOOCV} {
+		best, curve, err := SelectWidth(x, nil, Linear, crit)
+		if err != nil {
+			t.Fatalf("%v: %v", crit, err)
+		}
+		if len(curve) != len(defaultWidths(n)) {
+			t.Errorf("%v: expected %d scores. Got %d", crit, len(defaultWidths(n)), len(curve))
+		}
+		// neither interpolating nor oversmoothing a sine wave is optimal
+		if best <= 5 || best >= n {
+			t.Errorf("%v: unexpected best width %d", crit, best)
+		}
+		min := math.Inf(1)
+		var argmin int
+		for _, sc := range curve {
+			if sc.Score < min {
+				min, argmin = sc.Score, sc.Width
+			}
+		}
+		if argmin != best {
+			t.Errorf("%v: expected the best width to have the lowest score in the curve", crit)
+		}
+	}
+
+	if _, _, err := SelectWidth(x, []int{0}, Linear, GCV); err == nil {
+		t.Errorf("Expected an error for an invalid width")
+	}
+}
+
+func TestDefaultWidths(t *testing.T) {
+	for n := 3; n <= 3000; n++ {
+		widths := defaultWidths(n)
+		if len(widths) == 0 || len(widths) > maxDefaultWidths {
+			t.Fatalf("n = %d: expected 1 to %d widths. Got %d", n, maxDefaultWidths, len(widths))
+		}
+		for i, w := range widths {
+			if w%2 == 0 || w > n || (i > 0 && w <= widths[i-1]) {
+				t.Fatalf("n = %d: expected ascending odd widths up to n. Got %v", n, widths)
+			}
+		}
+		if last := widths[len(widths)-1]; last != n && last != n-1 {
+			t.Fatalf("n = %d: expected the widest candidate to be n, or n-1. Got %d", n, last)
+		}
+	}
+}
+
+func TestSelectWidthUnknownCriterion(t *testing.T) {
+	x := make([]float64, 50)
+	for i := range x {
+		x[i] = float64(i % 7)
+	}
+	if _, _, err := SelectWidth(x, nil, Linear, Criterion(42)); err == nil {
+		t.Error("Expected an error for an unknown criterion")
+	}
+}
@@ -0,0 +1,54 @@
+package loess
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+var benchWidths = []int{501, 5001}
+
+func benchmarkSmooth(b *testing.B, opts ...Option) {
+	x := make([]float64, 100000)
+	for i := range x {
+		x[i] = math.Sin(float64(i) / 100)
+	}
+	for _, width := range benchWidths {
+		b.Run(fmt.Sprintf("width=%d", width), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Smooth(x, width, 1, Linear, opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSmooth(b *testing.B)     { benchmarkSmooth(b) }
+func BenchmarkSmoothFast(b *testing.B) { benchmarkSmooth(b, WithFast()) }
+
+func benchmarkSurface(b *testing.B, eval Evaluation) {
+	var x [][]float64
+	var y []float64
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 100; j++ {
+			a, c := float64(i)/100, float64(j)/100
+			x = append(x, []float64{a, c})
+			y = append(y, math.Sin(3*a)*math.Cos(2*c))
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := NewSurface(500, x, y, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		s.SetEvaluation(eval)
+		if _, err = s.Fitted(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSurfaceDirect(b *testing.B)      { benchmarkSurface(b, Direct) }
+func BenchmarkSurfaceInterpolate(b *testing.B) { benchmarkSurface(b, Interpolate) }
@@ -0,0 +1,90 @@
+package loess
+
+import "reflect"
+
+// regression is the kind of a built in regression. The fast paths of smooth depend on it (see cachedRegress and fastRegress).
+type regression int
+
+const (
+	custom        regression = iota // not a built in regression
+	constantKind                    // Constant, or Polynomial(0)
+	linearKind                      // Linear, or Polynomial(1)
+	quadraticKind                   // Quadratic, or Polynomial(2)
+)
+
+// shiftInvariant checks if the operator rows of the regression depend only on the positions relative to the point being regressed.
+func (r regression) shiftInvariant() bool { return r != custom }
+
+// builtins holds the kinds of the top level built in regressions, by their code pointers.
+//
+// A code pointer only identifies a top level function: every closure of a function literal shares the code pointer of the literal.
+// So the regressions returned by Polynomial for degrees of 3 or more are not recognised, and are smoothed as custom regressions are.
+var builtins = map[uintptr]regression{
+	reflect.ValueOf(Constant).Pointer():  constantKind,
+	reflect.ValueOf(Linear).Pointer():    linearKind,
+	reflect.ValueOf(Quadratic).Pointer(): quadraticKind,
+}
+
+// kindOf returns the kind of fn, which is custom unless fn is Constant, Linear or Quadratic.
+func kindOf(fn WeightUpdate) regression {
+	if fn == nil {
+		return custom
+	}
+	return builtins[reflect.ValueOf(fn).Pointer()]
+}
+
+// regressFunc has the same signature as Regress.
+type regressFunc func(s *State, fn WeightUpdate, x, left, right float64) (float64, error)
+
+// cachedRegress returns a function with the same signature as Regress, which reuses the operator row of interior windows.
+//
+// For uniformly spaced data, every interior window (one where the point regressed is at offset half-1 from the left of a window of the full width)
+// has the same kernel weights, and a shift invariant regression turns them into the same operator row. So the row is computed once,
+// and each interior point is then a dot product of the row with its window. Windows near the edges are computed as usual.
+//
+// The row is only cached when the state has no positions, a built in regression is used, and the external weights (if any) are all equal.
+func (s *State) cachedRegress(width, half int, fn WeightUpdate) regressFunc {
+	if s.pos != nil || width >= len(s.x) || !kindOf(fn).shiftInvariant() || !uniform(s.e, len(s.x)) {
+		return Regress
+	}
+
+	var row []float64
+	offset := float64(half - 1)
+	return func(s *State, fn WeightUpdate, x, left, right float64) (float64, error) {
+		if x-left != offset || right-left != float64(width-1) {
+			return Regress(s, fn, x, left, right)
+		}
+		if row == nil {
+			retVal, err := Regress(s, fn, x, left, right)
+			if err == nil {
+				l, r := int(left), int(right)
+				row = make([]float64, r-l+1)
+				copy(row, s.w[l:r+1])
+			}
+			return retVal, err
+		}
+
+		var retVal float64
+		X := s.x[int(left) : int(right)+1]
+		for k, w := range row {
+			retVal += w * X[k]
+		}
+		return retVal, nil
+	}
+}
+
+// uniform checks that the external weights are all equal and positive, and so have no effect once normalized.
+func uniform(e []float64, n int) bool {
+	if len(e) == 0 {
+		return true
+	}
+	if len(e) < n || e[0] <= 0 {
+		return false
+	}
+	for _, v := range e[:n] {
+		if v != e[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gorgonia.org/dawson"
+)
+
+// uncached is a shift invariant regression that isn't recognized as such, so that the cache is bypassed.
+func uncached(s *State, x, left, right float64) error { return Linear(s, x, left, right) }
+
+func TestCachedSmooth(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	x := make([]float64, 500)
+	for i := range x {
+		x[i] = math.Sin(float64(i)/30) + r.NormFloat64()
+	}
+	ones := make([]float64, len(x))
+	for i := range ones {
+		ones[i] = 2
+	}
+
+	for _, width := range []int{4, 7, 35, 101} {
+		for _, jump := range []int{1, 3} {
+			expected := make([]float64, len(x))
+			smooth(New(width, x), width, jump, uncached, expected)
+
+			got, _ := Smooth(x, width, jump, Linear)
+			if !dawson.AllClose(expected, got, dawson.CloseEnoughF64) {
+				t.Errorf("Width %d, jump %d: cached smoothing differs from uncached smoothing", width, jump)
+			}
+
+			got, _ = UnsafeSmooth(NewWithExternal(width, x, ones), width, jump, Linear, nil)
+			if !dawson.AllClose(expected, got, dawson.CloseEnoughF64) {
+				t.Errorf("Width %d, jump %d: cached smoothing with uniform external weights differs from uncached smoothing", width, jump)
+			}
+		}
+	}
+
+	if !kindOf(Quadratic).shiftInvariant() || kindOf(uncached).shiftInvariant() {
+		t.Errorf("Unexpected shift invariance")
+	}
+	if uniform([]float64{1, 1, 0.5}, 3) || !uniform(nil, 3) || uniform([]float64{0, 0}, 2) {
+		t.Errorf("Unexpected uniformity")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	// only the top level regressions are recognised
+	scaled := func(k float64) WeightUpdate {
+		return func(s *State, x, left, right float64) error {
+			for j := int(left); j <= int(right); j++ {
+				s.w[j] *= k
+			}
+			return nil
+		}
+	}
+	cases := []struct {
+		name string
+		fn   WeightUpdate
+		want regression
+	}{
+		{"Constant", Constant, constantKind},
+		{"Linear", Linear, linearKind},
+		{"Quadratic", Quadratic, quadraticKind},
+		{"Polynomial(0)", Polynomial(0), constantKind},
+		{"Polynomial(1)", Polynomial(1), linearKind},
+		{"Polynomial(2)", Polynomial(2), quadraticKind},
+		{"Polynomial(5)", Polynomial(5), custom},
+		{"uncached", uncached, custom},
+		{"scaled", scaled(1), custom},
+		{"nil", nil, custom},
+	}
+	for _, c := range cases {
+		if got := kindOf(c.fn); got != c.want {
+			t.Errorf("%v: expected kind %d. Got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package loess
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Estimate is a local regression estimate at a point: the fitted value, and the first and second derivatives of the local polynomial.
+type Estimate struct {
+	Value     float64
+	Slope     float64
+	Curvature float64 // only estimated by local polynomials of degree >= 2
+}
+
+// RegressDerivatives performs a local polynomial regression of the given degree at x between left and right,
+// and returns the fitted value along with the derivatives of the local polynomial at x.
+// The derivatives are per unit of position (or index, if the state has no positions).
+func RegressDerivatives(s *State, degree int, x, left, right float64) (retVal Estimate, err error) {
+	if degree < 0 {
+		return retVal, errors.Errorf("Cannot perform local polynomial regression of degree %d", degree)
+	}
+	if err = s.localWeights(x, left, right); err != nil {
+		return
+	}
+	return localPolynomial(s, degree, x, int(left), int(right)), nil
+}
+
+// localPolynomial fits a polynomial of the given degree to the window by weighted least squares, using the weights in the state.
+// The coefficients are found by a QR decomposition of the design matrix augmented with the data, [√W A | √W X], whose R factor
+// contains R and Q'√W X. If the design matrix is rank deficient, the degree is lowered.
+func localPolynomial(s *State, degree int, x float64, left, right int) Estimate {
+	W, X := s.w, s.x
+	m := right - left + 1
+	q := degree + 1
+
+	var scale float64
+	for j := left; j <= right; j++ {
+		scale = math.Max(scale, math.Abs(s.At(j)-x))
+	}
+	if scale == 0 || degree == 0 {
+		var v float64
+		for j := left; j <= right; j++ {
+			v += W[j] * X[j]
+		}
+		return Estimate{Value: v}
+	}
+
+	a := make([]float64, m*(q+1))
+	for j := left; j <= right; j++ {
+		u := (s.At(j) - x) / scale
+		sw := math.Sqrt(W[j])
+		v := sw
+		for k := 0; k < q; k++ {
+			a[k*m+j-left] = v
+			v *= u
+		}
+		a[q*m+j-left] = sw * X[j]
+	}
+	r, ok := householderR(a, m, q+1, q)
+	if !ok {
+		return localPolynomial(s, degree-1, x, left, right)
+	}
+
+	// back substitute R c = Q'√W X
+	p := q + 1
+	coef := make([]float64, q)
+	for i := q - 1; i >= 0; i-- {
+		v := r[i*p+q]
+		for k := i + 1; k < q; k++ {
+			v -= r[i*p+k] * coef[k]
+		}
+		coef[i] = v / r[i*p+i]
+	}
+
+	retVal := Estimate{Value: coef[0], Slope: coef[1] / scale}
+	if q > 2 {
+		retVal.Curvature = 2 * coef[2] / (scale * scale)
+	}
+	return retVal
+}
+
+// Derivatives is the result of SmoothDerivatives.
+type Derivatives struct {
+	Value     []float64
+	Slope     []float64
+	Curvature []float64
+}
+
+// SmoothDerivatives is like Smooth with a local polynomial of the given degree, but also returns the local slope (and curvature, for degree >= 2)
+// of every point. Points skipped by the jump are linearly interpolated.
+func SmoothDerivatives(x []float64, width, jump, degree int) (Derivatives, error) {
+	return UnsafeSmoothDerivatives(New(width, x), jump, degree)
+}
+
+// UnsafeSmoothDerivatives is like SmoothDerivatives, except the state (with its kernel, positions and external weights) is passed in.
+func UnsafeSmoothDerivatives(s *State, jump, degree int) (Derivatives, error) {
+	if jump <= 0 {
+		return Derivatives{}, errors.Errorf("Cannot work with jump == 0")
+	}
+	if degree < 0 {
+		return Derivatives{}, errors.Errorf("Cannot perform local polynomial regression of degree %d", degree)
+	}
+	n := len(s.x)
+	retVal := Derivatives{
+		Value:     make([]float64, n),
+		Slope:     make([]float64, n),
+		Curvature: make([]float64, n),
+	}
+	if n == 0 {
+		return retVal, nil
+	}
+
+	last := 0
+	for i := 0; ; i += jump {
+		if i >= n {
+			i = n - 1
+		}
+		left, right := s.Bounds(i)
+		if est, err := RegressDerivatives(s, degree, s.At(i), float64(left), float64(right)); err == nil {
+			retVal.Value[i] = est.Value
+			retVal.Slope[i] = est.Slope
+			retVal.Curvature[i] = est.Curvature
+		} else {
+			retVal.Value[i] = s.x[i]
+		}
+
+		if i-last > 1 {
+			dx := s.At(i) - s.At(last)
+			for j := last + 1; j < i; j++ {
+				frac := 0.0
+				if dx != 0 {
+					frac = (s.At(j) - s.At(last)) / dx
+				}
+				retVal.Value[j] = lerp(retVal.Value[last], retVal.Value[i], frac)
+				retVal.Slope[j] = lerp(retVal.Slope[last], retVal.Slope[i], frac)
+				retVal.Curvature[j] = lerp(retVal.Curvature[last], retVal.Curvature[i], frac)
+			}
+		}
+		last = i
+		if i == n-1 {
+			break
+		}
+	}
+	return retVal, nil
+}
+
+func lerp(a, b, frac float64) float64 { return a + (b-a)*frac }
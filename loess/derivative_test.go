@@ -0,0 +1,55 @@
+package loess
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSmoothDerivatives(t *testing.T) {
+	n := 40
+	x := make([]float64, n)
+	for i := range x {
+		u := float64(i)
+		x[i] = 0.5*u*u - 3*u + 2
+	}
+
+	d, err := SmoothDerivatives(x, 9, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range x {
+		u := float64(i)
+		if math.Abs(d.Value[i]-x[i]) > 1e-6 || math.Abs(d.Slope[i]-(u-3)) > 1e-6 || math.Abs(d.Curvature[i]-1) > 1e-6 {
+			t.Errorf("At %d: expected (%v, %v, 1). Got %+v", i, x[i], u-3, d)
+			break
+		}
+	}
+
+	// a local linear fit of a line gives its slope everywhere, even with jumps and irregular positions
+	pos := []float64{0, 0.5, 1.5, 2, 4, 4.5, 7, 8, 8.1, 10}
+	line := make([]float64, len(pos))
+	for i := range pos {
+		line[i] = 3*pos[i] - 1
+	}
+	s, err := NewWithPositions(4, pos, line, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = UnsafeSmoothDerivatives(s, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pos {
+		if math.Abs(d.Value[i]-line[i]) > 1e-9 || math.Abs(d.Slope[i]-3) > 1e-9 || d.Curvature[i] != 0 {
+			t.Errorf("At %d: expected (%v, 3, 0). Got (%v, %v, %v)", i, line[i], d.Value[i], d.Slope[i], d.Curvature[i])
+		}
+	}
+
+	est, err := RegressDerivatives(New(5, x), 0, 10, 8, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Slope != 0 {
+		t.Errorf("Expected a local constant to have no slope. Got %v", est.Slope)
+	}
+}
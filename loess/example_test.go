@@ -0,0 +1,49 @@
+package loess_test
+
+import (
+	"fmt"
+
+	"github.com/chewxy/stl/loess"
+)
+
+// RidgeLinear is a local linear regression whose slope is shrunk towards 0 by a ridge penalty.
+// It only uses the exported API of package loess, as a regression implemented in another package would.
+func RidgeLinear(penalty float64) loess.WeightUpdate {
+	return func(s *loess.State, x, left, right float64) error {
+		W := s.W()
+		l, r := int(left), int(right)
+
+		var mean, variance float64
+		for j := l; j <= r; j++ {
+			mean += W[j] * s.At(j)
+		}
+		for j := l; j <= r; j++ {
+			d := s.At(j) - mean
+			variance += W[j] * d * d
+		}
+
+		beta := (x - mean) / (variance + penalty)
+		for j := l; j <= r; j++ {
+			W[j] *= 1 + beta*(s.At(j)-mean)
+		}
+		return nil
+	}
+}
+
+func Example_customRegression() {
+	a := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	// with no penalty, this is a local linear regression, which reproduces a line
+	if smoothed, err := loess.Smooth(a, 5, 1, RidgeLinear(0)); err == nil {
+		fmt.Printf("Smoothed %1.2f\n", smoothed)
+	}
+
+	// with a large penalty, the slope is shrunk and the fit tends to a local constant - most visibly at the edges
+	if smoothed, err := loess.Smooth(a, 5, 1, RidgeLinear(100)); err == nil {
+		fmt.Printf("Smoothed %1.2f\n", smoothed)
+	}
+
+	// Output:
+	// Smoothed [1.00 2.00 3.00 4.00 5.00 6.00 7.00 8.00 9.00 10.00]
+	// Smoothed [2.01 2.22 3.00 4.00 5.00 6.00 7.00 8.00 8.78 8.99]
+}
@@ -0,0 +1,165 @@
+package loess
+
+import "math"
+
+// fastTol is the fraction of the total kernel weight below which the weight of a window is considered too small for the fast path.
+// Such windows are regressed exactly.
+const fastTol = 1e-8
+
+// SetFast sets whether Smooth and UnsafeSmooth use the fast path for local linear and local constant regression. See WithFast.
+func (s *State) SetFast(fast bool) { s.fast = fast }
+
+// Fast returns whether the fast path is used.
+func (s *State) Fast() bool { return s.fast }
+
+// fastRegress returns a function with the same signature as Regress, which looks up the fitted values of interior windows
+// computed by convolution (see WithFast). If the fast path cannot be used, cachedRegress is used instead.
+func (s *State) fastRegress(width, half int, fn WeightUpdate) regressFunc {
+	kind := kindOf(fn)
+	if !s.fast || s.pos != nil || width >= len(s.x) || (kind != linearKind && kind != constantKind) {
+		return s.cachedRegress(width, half, fn)
+	}
+
+	fitted, ok := s.convolve(width, half, kind == linearKind)
+	offset := float64(half - 1)
+	return func(s *State, fn WeightUpdate, x, left, right float64) (float64, error) {
+		i := int(x)
+		if x-left != offset || right-left != float64(width-1) || !ok[i] {
+			return Regress(s, fn, x, left, right)
+		}
+		return fitted[i], nil
+	}
+}
+
+// fastBlock is the least size of the FFTs of the fast path, which convolves the series block by block (see convolve).
+const fastBlock = 1 << 12
+
+// convolve computes the local linear (or local constant) fitted values of every interior point by convolution.
+//
+// Every interior window has the same kernel weights K(d), where d = j - i is the offset from the point i being regressed.
+// With the external weights e, the local linear fit at i only needs the moments
+//
+//	M_k(i) = Σ_d d^k K(d) e[i+d]       (k = 0, 1, 2)
+//	T_k(i) = Σ_d d^k K(d) e[i+d] x[i+d] (k = 0, 1)
+//
+// which are convolutions of the data with the (reversed) kernel, computed by FFT.
+// The data is centered on its weighted mean beforehand, which keeps the rounding error of the FFT proportional to the spread of the data
+// rather than its magnitude. ok[i] reports whether fitted[i] was computed; it is false for the edges and for windows with too little weight.
+//
+// The convolutions are computed block by block (overlap-save), with FFTs of size N, the power of 2 that is at least max(2·width, 4096),
+// or n + width - 1 if that is smaller. Each block yields N - width + 1 points, so the time is O(n log N), and the memory is that of
+// the results (9 bytes a point) and of fifteen buffers of N numbers - under 1 MB for windows of up to 2048 points,
+// rather than buffers the size of the whole series.
+func (s *State) convolve(width, half int, linear bool) (fitted []float64, ok []bool) {
+	n := len(s.x)
+	a := half - 1     // points to the left of an interior point
+	b := width - half // points to the right of an interior point
+
+	// the kernel weights of an interior window, without the external weights
+	e := s.e
+	s.e = nil
+	err := s.localWeights(float64(a), 0, float64(width-1))
+	s.e = e
+	if err != nil {
+		return nil, make([]bool, n)
+	}
+	h0 := make([]float64, width)
+	h1 := make([]float64, width)
+	h2 := make([]float64, width)
+	var ksum float64
+	for k := range h0 {
+		d := float64(width - 1 - k - a)
+		kw := s.w[width-1-k]
+		h0[k] = kw
+		h1[k] = d * kw
+		h2[k] = d * d * kw
+		ksum += kw
+	}
+
+	weight := func(j int) float64 {
+		if len(e) > j {
+			return e[j]
+		}
+		return 1
+	}
+	var sumE, sumEX, maxE float64
+	for j, x := range s.x {
+		w := weight(j)
+		sumE += w
+		sumEX += w * x
+		maxE = math.Max(maxE, w)
+	}
+	var c float64
+	if sumE > 0 {
+		c = sumEX / sumE
+	}
+
+	size := nextPow2(2 * width)
+	if size < fastBlock {
+		size = fastBlock
+	}
+	if whole := nextPow2(n + width - 1); whole < size {
+		size = whole
+	}
+	step := size - width + 1 // the points computed by each block
+
+	cbuf := func() []complex128 { return make([]complex128, size) }
+	fbuf := func() []float64 { return make([]float64, size) }
+	tw := twiddles(size)
+	z, fh0, fh1, fh2 := cbuf(), cbuf(), cbuf(), cbuf()
+	fftPair(h0, h1, fh0, fh1, z, tw)
+	fftPair(h2, nil, fh2, nil, z, tw)
+
+	ev, xc := fbuf(), fbuf()
+	fe, fx, p, q := cbuf(), cbuf(), cbuf(), cbuf()
+	m0, m1, m2, t0, t1 := fbuf(), fbuf(), fbuf(), fbuf(), fbuf()
+	prod := func(dst, f, g []complex128) []complex128 {
+		for k := range dst {
+			dst[k] = f[k] * g[k]
+		}
+		return dst
+	}
+
+	// see Linear
+	thresh := 0.01 * float64(n-1)
+	tol := fastTol * ksum * maxE
+
+	fitted = make([]float64, n)
+	ok = make([]bool, n)
+	for k0 := width - 1; k0 < n; k0 += step {
+		// the kth value of the full convolutions depends on the points k-width+1..k,
+		// so the block of values k0..k0+step-1 depends on the segment of the series from k0-width+1
+		start := k0 - width + 1
+		for j := range ev {
+			ev[j], xc[j] = 0, 0
+			if start+j < n {
+				ev[j] = weight(start + j)
+				xc[j] = ev[j] * (s.x[start+j] - c)
+			}
+		}
+		fftPair(ev, xc, fe, fx, z, tw)
+		ifftPair(prod(p, fh0, fe), prod(q, fh1, fe), z, tw, m0, m1)
+		ifftPair(prod(p, fh2, fe), prod(q, fh0, fx), z, tw, m2, t0)
+		ifftPair(prod(p, fh1, fx), nil, z, tw, t1, nil)
+
+		// the first width-1 values of the circular convolutions wrap around, and are discarded
+		for j := width - 1; j < size && start+j < n; j++ {
+			i := start + j - b
+			if m0[j] <= tol {
+				continue
+			}
+			v := t0[j] / m0[j]
+			if linear {
+				mean := m1[j] / m0[j]
+				variance := m2[j]/m0[j] - mean*mean
+				if variance >= thresh*thresh {
+					beta := -mean / variance
+					v = (t0[j] + beta*(t1[j]-mean*t0[j])) / m0[j]
+				}
+			}
+			fitted[i] = v + c
+			ok[i] = true
+		}
+	}
+	return fitted, ok
+}
@@ -0,0 +1,106 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func maxAbsDiff(a, b []float64) float64 {
+	var retVal float64
+	for i := range a {
+		retVal = math.Max(retVal, math.Abs(a[i]-b[i]))
+	}
+	return retVal
+}
+
+func TestFastSmooth(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	x := make([]float64, 5000)
+	e := make([]float64, len(x))
+	for i := range x {
+		x[i] = 1000 + math.Sin(float64(i)/50) + r.NormFloat64()
+		e[i] = r.Float64()
+	}
+	e[2000] = 0
+
+	cases := []struct {
+		name   string
+		fn     WeightUpdate
+		width  int
+		jump   int
+		e      []float64
+		kernel Kernel
+	}{
+		{"linear", Linear, 101, 1, nil, Tricube},
+		{"linear even width", Linear, 100, 1, nil, Tricube},
+		{"linear jump", Linear, 101, 7, nil, Tricube},
+		{"linear external", Linear, 301, 1, e, Tricube},
+		{"linear gaussian", Linear, 101, 1, e, Gaussian},
+		{"constant", Constant, 51, 1, e, Epanechnikov},
+		{"quadratic", Quadratic, 51, 1, nil, Tricube}, // not supported: smoothed exactly
+	}
+	for _, c := range cases {
+		exact := NewWithExternal(c.width, x, c.e)
+		exact.SetKernel(c.kernel)
+		expected, err := UnsafeSmooth(exact, c.width, c.jump, c.fn, make([]float64, len(x)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fast := NewWithExternal(c.width, x, c.e)
+		fast.SetKernel(c.kernel)
+		got, err := UnsafeSmooth(fast, c.width, c.jump, c.fn, make([]float64, len(x)), WithFast())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := maxAbsDiff(expected, got); d > 1e-9 {
+			t.Errorf("%s: fast path differs from the exact path by %v", c.name, d)
+		}
+		if fast.Fast() {
+			t.Errorf("%s: expected the fast option to be restored", c.name)
+		}
+	}
+}
+
+func TestFastSmoothInPlace(t *testing.T) {
+	x := make([]float64, 1000)
+	for i := range x {
+		x[i] = math.Sin(float64(i) / 20)
+	}
+	expected, err := Smooth(x, 101, 1, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(101, x)
+	got, err := UnsafeSmooth(s, 101, 1, Linear, x, WithFast())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxAbsDiff(expected, got); d > 1e-9 {
+		t.Errorf("In place fast path differs from the out of place exact path by %v", d)
+	}
+}
+
+func TestFastSmoothBlocks(t *testing.T) {
+	// a series long enough to be convolved in several blocks, with a window wider than the first of them overlaps
+	r := rand.New(rand.NewSource(1337))
+	x := make([]float64, 20000)
+	e := make([]float64, len(x))
+	for i := range x {
+		x[i] = 50 + 10*math.Sin(float64(i)/300) + r.NormFloat64()
+		e[i] = 0.5 + r.Float64()
+	}
+	for _, width := range []int{1001, 3001} {
+		expected, err := UnsafeSmooth(NewWithExternal(width, x, e), width, 1, Linear, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := UnsafeSmooth(NewWithExternal(width, x, e), width, 1, Linear, nil, WithFast())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := maxAbsDiff(expected, got); d > 1e-9 {
+			t.Errorf("Width %d: fast path differs from the exact path by %v", width, d)
+		}
+	}
+}
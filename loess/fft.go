@@ -0,0 +1,108 @@
+package loess
+
+import (
+	"math"
+	"math/bits"
+)
+
+// twiddles returns the n/2 twiddle factors of the forward transform of size n. They are computed directly rather than by recurrence, for accuracy.
+func twiddles(n int) []complex128 {
+	retVal := make([]complex128, n/2)
+	for k := range retVal {
+		sin, cos := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		retVal[k] = complex(cos, sin)
+	}
+	return retVal
+}
+
+// fft computes the discrete Fourier transform of a in place, with the twiddle factors of its size (see twiddles). len(a) must be a power of 2.
+// If inverse is true, the inverse transform (including the 1/N scaling) is computed instead.
+func fft(a, twiddle []complex128, inverse bool) {
+	n := len(a)
+	if n <= 1 {
+		return
+	}
+	shift := 64 - uint(bits.TrailingZeros(uint(n)))
+	for i := 0; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := twiddle[k*step]
+				if inverse {
+					w = conj(w)
+				}
+				t := w * a[start+k+half]
+				a[start+k+half] = a[start+k] - t
+				a[start+k] += t
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range a {
+			a[i] *= scale
+		}
+	}
+}
+
+// fftPair computes the transforms fa and fb of the two real sequences a and b (zero padded to len(z)) with a single complex transform in z.
+// b may be nil, in which case fb is not written and may be nil too.
+func fftPair(a, b []float64, fa, fb, z, twiddle []complex128) {
+	n := len(z)
+	for i := range z {
+		var re, im float64
+		if i < len(a) {
+			re = a[i]
+		}
+		if i < len(b) {
+			im = b[i]
+		}
+		z[i] = complex(re, im)
+	}
+	fft(z, twiddle, false)
+
+	for k := range z {
+		c := conj(z[(n-k)%n])
+		fa[k] = (z[k] + c) / 2
+		if b != nil {
+			fb[k] = (z[k] - c) * complex(0, -0.5)
+		}
+	}
+}
+
+// ifftPair computes the inverse transforms a and b of fa and fb, which must be the transforms of real sequences, with a single complex transform in z.
+// fb and b may be nil, in which case only a is computed.
+func ifftPair(fa, fb, z, twiddle []complex128, a, b []float64) {
+	for k := range z {
+		z[k] = fa[k]
+		if fb != nil {
+			z[k] += complex(0, 1) * fb[k]
+		}
+	}
+	fft(z, twiddle, true)
+	for i, v := range z {
+		a[i] = real(v)
+		if b != nil {
+			b[i] = imag(v)
+		}
+	}
+}
+
+func conj(c complex128) complex128 { return complex(real(c), -imag(c)) }
+
+// nextPow2 returns the smallest power of 2 that is >= n.
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << uint(bits.Len(uint(n-1)))
+}
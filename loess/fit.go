@@ -0,0 +1,327 @@
+package loess
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// exactLimit is the largest amount of work (n * width * width) for which TwoDelta is computed exactly.
+// Beyond that, it is approximated by OneDelta, and the Fit is marked Approximate.
+const exactLimit = 1 << 28
+
+// Fit is a LOESS fit of every point of the data, along with the statistics of the fit, in the manner of R's predict.loess(se = TRUE).
+//
+// The LOESS fitted values are linear in the data: Fitted = L·X, where L is the operator (or hat) matrix of the smoother.
+// The statistics are derived from L.
+//
+// The fit is that of Smooth with jump == 1, where every point is fitted by its own regression. SE, DF and the intervals
+// do not describe the fits of Smooth with jump > 1 (or with the fast path or interpolation), whose points in between are interpolated.
+type Fit struct {
+	Fitted    []float64
+	Residuals []float64
+
+	// Leverage is the diagonal of the operator matrix - the weight each point has on its own fitted value.
+	Leverage []float64
+
+	// SE is the pointwise standard error of the fitted values.
+	SE []float64
+
+	// ResidualSE is the residual standard error: sqrt(RSS / OneDelta)
+	ResidualSE float64
+
+	// ENP is the equivalent number of parameters: the trace of L.
+	ENP float64
+
+	// OneDelta is trace((I-L)'(I-L)), and TwoDelta is trace(((I-L)'(I-L))²).
+	OneDelta, TwoDelta float64
+
+	// DF is the "lookup" degrees of freedom: OneDelta² / TwoDelta.
+	DF float64
+
+	// Approximate is true if TwoDelta was too costly to compute exactly (see UnsafeSmoothFit), and was approximated by OneDelta.
+	Approximate bool
+}
+
+// SmoothFit fits every point of x with the provided width and update function, and computes the statistics of the fit.
+func SmoothFit(x []float64, width int, fn WeightUpdate) (*Fit, error) {
+	return UnsafeSmoothFit(New(width, x), fn)
+}
+
+// UnsafeSmoothFit is like SmoothFit, except the state is passed in. States with positions and external weights are supported.
+//
+// TwoDelta is computed exactly in O(n·width²) time. When n·width² exceeds 2^28, it is approximated by OneDelta instead, so DF = OneDelta,
+// and Approximate is set. That overstates DF somewhat, but DF is then in the hundreds at least, where the t quantiles used by Interval
+// are close to those of the normal distribution either way.
+func UnsafeSmoothFit(s *State, fn WeightUpdate) (*Fit, error) {
+	f, rss, err := s.fit(fn)
+	if err != nil {
+		return nil, err
+	}
+	if f.OneDelta <= 0 {
+		return nil, errors.Errorf("Smoother interpolates the data (OneDelta = %v); the residual standard error is undefined", f.OneDelta)
+	}
+	f.ResidualSE = math.Sqrt(rss / f.OneDelta)
+	for i := range f.SE {
+		f.SE[i] = f.ResidualSE * math.Sqrt(f.SE[i])
+	}
+
+	n, w := len(s.x), s.width
+	if w > n {
+		w = n
+	}
+	if float64(n)*float64(w)*float64(w) <= exactLimit {
+		f.TwoDelta = s.twoDelta(fn)
+	} else {
+		f.TwoDelta = f.OneDelta
+		f.Approximate = true
+	}
+	f.DF = f.OneDelta * f.OneDelta / f.TwoDelta
+	return f, nil
+}
+
+// fit computes the fitted values, residuals, leverages, ENP and OneDelta, and returns the residual sum of squares.
+// SE holds the sum of squares of each operator row, and has yet to be scaled by the residual standard error.
+func (s *State) fit(fn WeightUpdate) (f *Fit, rss float64, err error) {
+	n := len(s.x)
+	if n < 2 {
+		return nil, 0, errors.Errorf("Cannot fit %d points", n)
+	}
+
+	f = &Fit{
+		Fitted:    make([]float64, n),
+		Residuals: make([]float64, n),
+		Leverage:  make([]float64, n),
+		SE:        make([]float64, n),
+	}
+
+	buf := make([]float64, 0, s.width)
+	for i := 0; i < n; i++ {
+		var left int
+		left, buf = s.row(fn, i, buf[:0])
+		var fitted, sumsq float64
+		for k, l := range buf {
+			fitted += l * s.x[left+k]
+			sumsq += l * l
+		}
+		lii := buf[i-left]
+
+		f.Fitted[i] = fitted
+		f.Residuals[i] = s.x[i] - fitted
+		f.Leverage[i] = lii
+		f.SE[i] = sumsq
+		f.ENP += lii
+		f.OneDelta += 1 - 2*lii + sumsq
+		rss += f.Residuals[i] * f.Residuals[i]
+	}
+	return f, rss, nil
+}
+
+// Interval returns the pointwise confidence interval of the fitted values at the given confidence level (e.g. 0.95).
+func (f *Fit) Interval(level float64) (lower, upper []float64) {
+	t := tQuantile(1-(1-level)/2, f.DF)
+	lower = make([]float64, len(f.Fitted))
+	upper = make([]float64, len(f.Fitted))
+	for i := range f.Fitted {
+		lower[i] = f.Fitted[i] - t*f.SE[i]
+		upper[i] = f.Fitted[i] + t*f.SE[i]
+	}
+	return lower, upper
+}
+
+// OperatorRow returns the row of the operator matrix for a regression at x between left and right:
+// the fitted value at x is the dot product of the row and the data.
+func OperatorRow(s *State, fn WeightUpdate, x, left, right float64) ([]float64, error) {
+	if err := s.localWeights(x, left, right); err != nil {
+		return nil, err
+	}
+	if err := fn(s, x, left, right); err != nil {
+		return nil, err
+	}
+	retVal := make([]float64, len(s.x))
+	l, r := int(left), int(right)
+	copy(retVal[l:r+1], s.w[l:r+1])
+	return retVal, nil
+}
+
+// Bounds returns the window of the width nearest neighbours of the ith point. This is the window used by Smooth when jump == 1.
+func (s *State) Bounds(i int) (left, right int) {
+	if s.pos != nil {
+		return Window(s.pos, s.pos[i], s.width)
+	}
+	n := len(s.x)
+	if s.width >= n {
+		return 0, n - 1
+	}
+	half := (s.width + 1) / 2
+	left = i - half + 1
+	switch {
+	case left < 0:
+		left = 0
+	case left > n-s.width:
+		left = n - s.width
+	}
+	return left, left + s.width - 1
+}
+
+// row computes the operator row of the ith point, restricted to its window, which starts at left.
+// The row is appended to buf. If the regression fails, the row is that of the identity, following Smooth.
+func (s *State) row(fn WeightUpdate, i int, buf []float64) (left int, row []float64) {
+	left, right := s.Bounds(i)
+	l, r := float64(left), float64(right)
+	if err := s.localWeights(s.At(i), l, r); err == nil {
+		if err = fn(s, s.At(i), l, r); err == nil {
+			return left, append(buf, s.w[left:right+1]...)
+		}
+	}
+	for j := left; j <= right; j++ {
+		if j == i {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+	return left, buf
+}
+
+// twoDelta computes trace(M²) exactly, where M = (I-L)'(I-L) is symmetric and banded.
+//
+// Rows of (I-L) are computed in order and kept only while their windows may still overlap the current column,
+// so the memory used is O(width²) rather than O(n²).
+func (s *State) twoDelta(fn WeightUpdate) float64 {
+	n := len(s.x)
+	type arow struct {
+		i, left int
+		a       []float64 // row i of (I-L), restricted to [left, left+len(a))
+	}
+	var rows []arow
+	next := 0
+	var retVal float64
+	m := make([]float64, 0, 2*s.width)
+	for j := 0; j < n; j++ {
+		// compute every row whose window starts at or before j
+		for next < n {
+			left, right := s.Bounds(next)
+			if left > j {
+				break
+			}
+			_, r := s.row(fn, next, make([]float64, 0, right-left+1))
+			for k := range r {
+				r[k] = -r[k]
+			}
+			r[next-left]++
+			rows = append(rows, arow{next, left, r})
+			next++
+		}
+		// drop rows whose windows end before j
+		for len(rows) > 0 && rows[0].left+len(rows[0].a) <= j {
+			rows = rows[1:]
+		}
+
+		// M[j][k] for k >= j
+		m = m[:0]
+		for _, r := range rows {
+			if j < r.left || j >= r.left+len(r.a) {
+				continue
+			}
+			aij := r.a[j-r.left]
+			for k := j; k < r.left+len(r.a); k++ {
+				for len(m) <= k-j {
+					m = append(m, 0)
+				}
+				m[k-j] += aij * r.a[k-r.left]
+			}
+		}
+		for k, v := range m {
+			if k == 0 {
+				retVal += v * v
+			} else {
+				retVal += 2 * v * v
+			}
+		}
+	}
+	return retVal
+}
+
+// tQuantile is the quantile function of Student's t distribution with df degrees of freedom, found by bisection of the CDF.
+func tQuantile(p, df float64) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	if p < 0.5 {
+		return -tQuantile(1-p, df)
+	}
+	lo, hi := 0.0, 1.0
+	for tCDF(hi, df) < p {
+		hi *= 2
+		if hi > 1e10 {
+			return math.Inf(1)
+		}
+	}
+	for i := 0; i < 100 && hi-lo > 1e-12*hi; i++ {
+		mid := (lo + hi) / 2
+		if tCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// tCDF is the cumulative distribution function of Student's t distribution, for t >= 0.
+func tCDF(t, df float64) float64 {
+	x := df / (df + t*t)
+	return 1 - 0.5*regIncBeta(df/2, 0.5, x)
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b), evaluated by its continued fraction.
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaCF(b, a, 1-x)/b
+	}
+	return front * betaCF(a, b, x) / a
+}
+
+// betaCF evaluates the continued fraction of the incomplete beta function by the modified Lentz method.
+func betaCF(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		for step := 0; step < 2; step++ {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+			num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
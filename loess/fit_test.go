@@ -0,0 +1,110 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gorgonia.org/dawson"
+)
+
+func TestSmoothFit(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	n := 40
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = math.Sin(float64(i)/5) + 0.1*r.NormFloat64()
+	}
+
+	for _, width := range []int{7, 12, 50} {
+		f, err := SmoothFit(x, width, Linear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		smoothed, _ := Smooth(x, width, 1, Linear)
+		if !dawson.AllClose(smoothed, f.Fitted, dawson.CloseEnoughF64) {
+			t.Errorf("Width %d: expected the fitted values to be the same as Smooth", width)
+		}
+
+		// build the dense operator matrix to check the statistics
+		L := make([][]float64, n)
+		s := New(width, x)
+		for i := range L {
+			left, right := s.Bounds(i)
+			if L[i], err = OperatorRow(s, Linear, float64(i), float64(left), float64(right)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var enp, one, two float64
+		M := make([][]float64, n)
+		for i := range M {
+			enp += L[i][i]
+			M[i] = make([]float64, n)
+			for j := range M[i] {
+				for k := 0; k < n; k++ {
+					aki, akj := -L[k][i], -L[k][j]
+					if k == i {
+						aki++
+					}
+					if k == j {
+						akj++
+					}
+					M[i][j] += aki * akj
+				}
+			}
+			one += M[i][i]
+		}
+		for i := range M {
+			for j := range M[i] {
+				two += M[i][j] * M[j][i]
+			}
+		}
+		if !dawson.CloseF64(enp, f.ENP) || !dawson.CloseF64(one, f.OneDelta) || !dawson.CloseF64(two, f.TwoDelta) {
+			t.Errorf("Width %d: expected ENP %v, OneDelta %v, TwoDelta %v. Got %v, %v, %v", width, enp, one, two, f.ENP, f.OneDelta, f.TwoDelta)
+		}
+
+		lower, upper := f.Interval(0.95)
+		for i := range lower {
+			if !(lower[i] < f.Fitted[i] && f.Fitted[i] < upper[i]) {
+				t.Errorf("Width %d: fitted value %v not within [%v, %v]", width, f.Fitted[i], lower[i], upper[i])
+			}
+		}
+	}
+}
+
+func TestSmoothFitApproximate(t *testing.T) {
+	x := make([]float64, 20000)
+	for i := range x {
+		x[i] = math.Sin(float64(i) / 50)
+	}
+	for _, c := range []struct {
+		width  int
+		approx bool
+	}{{101, false}, {121, true}} {
+		f, err := SmoothFit(x, c.width, Linear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Approximate != c.approx {
+			t.Errorf("Width %d: expected Approximate to be %v", c.width, c.approx)
+		}
+		if c.approx && (f.TwoDelta != f.OneDelta || f.DF != f.OneDelta) {
+			t.Errorf("Width %d: expected TwoDelta and DF to be approximated by OneDelta %v. Got %v and %v", c.width, f.OneDelta, f.TwoDelta, f.DF)
+		}
+	}
+}
+
+func TestTQuantile(t *testing.T) {
+	cases := []struct{ p, df, correct float64 }{
+		{0.975, 1, 12.7062},
+		{0.975, 10, 2.228139},
+		{0.95, 5, 2.015048},
+		{0.025, 10, -2.228139},
+		{0.975, 1e6, 1.959966},
+	}
+	for _, c := range cases {
+		if got := tQuantile(c.p, c.df); math.Abs(got-c.correct) > 1e-4 {
+			t.Errorf("t quantile(%v, %v): expected %v. Got %v", c.p, c.df, c.correct, got)
+		}
+	}
+}
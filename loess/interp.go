@@ -0,0 +1,84 @@
+package loess
+
+import (
+	"fmt"
+	"math"
+)
+
+// Interpolation is the method by which the points skipped between jumps are filled in. The zero value is linear interpolation.
+type Interpolation int
+
+const (
+	// LinearInterpolation joins the smoothed points with straight lines. The result has kinks at every smoothed point.
+	LinearInterpolation Interpolation = iota
+	// HermiteInterpolation joins the smoothed points with cubic Hermite splines, using the slopes of local linear fits at the smoothed points,
+	// as netlib's loess does. The result has a continuous first derivative.
+	HermiteInterpolation
+)
+
+func (m Interpolation) String() string {
+	switch m {
+	case LinearInterpolation:
+		return "Linear"
+	case HermiteInterpolation:
+		return "Hermite"
+	}
+	return fmt.Sprintf("Interpolation(%d)", int(m))
+}
+
+// Interpolation returns the method by which the points skipped between jumps are filled in.
+func (s *State) Interpolation() Interpolation { return s.interp }
+
+// SetInterpolation sets the method by which the points skipped between jumps are filled in. The default is LinearInterpolation.
+func (s *State) SetInterpolation(m Interpolation) { s.interp = m }
+
+// slope returns the slope of the local linear fit at x between left and right, or NaN if the fit fails.
+func (s *State) slope(x, left, right float64) float64 {
+	est, err := RegressDerivatives(s, 1, x, left, right)
+	if err != nil {
+		return math.NaN()
+	}
+	return est.Slope
+}
+
+// slopeBuffers returns the slice the slopes of the smoothed points are computed into, if any, and the slopes to interpolate with (see interpolate).
+// The slopes are computed if they are requested (see WithSlopes), or if the skipped points are interpolated by Hermite splines.
+func (s *State) slopeBuffers(size, jump int) (slopes, hermite []float64) {
+	slopes = s.slopes
+	if slopes == nil && jump > 1 && s.interp == HermiteInterpolation {
+		slopes = make([]float64, size)
+	}
+	if s.interp == HermiteInterpolation {
+		hermite = slopes
+	}
+	return slopes, hermite
+}
+
+// interpolate fills in retVal between the smoothed points a and b.
+// If slopes is nil, or the slope at either point is unknown, the points are joined by a straight line. Otherwise by a cubic Hermite spline.
+func (s *State) interpolate(retVal, slopes []float64, a, b int) {
+	h := s.At(b) - s.At(a)
+	if h == 0 {
+		for j := a + 1; j < b; j++ {
+			retVal[j] = retVal[a]
+		}
+		return
+	}
+	y0, y1 := retVal[a], retVal[b]
+	if slopes == nil || math.IsNaN(slopes[a]) || math.IsNaN(slopes[b]) {
+		for j := a + 1; j < b; j++ {
+			retVal[j] = y0 + (y1-y0)*(s.At(j)-s.At(a))/h
+		}
+		return
+	}
+	m0, m1 := slopes[a]*h, slopes[b]*h
+	for j := a + 1; j < b; j++ {
+		retVal[j] = hermite(y0, y1, m0, m1, (s.At(j)-s.At(a))/h)
+	}
+}
+
+// hermite evaluates the cubic Hermite spline on [0, 1] with values y0, y1 and derivatives m0, m1 at the ends, at t.
+func hermite(y0, y1, m0, m1, t float64) float64 {
+	t2, t3 := t*t, t*t*t
+	return (2*t3-3*t2+1)*y0 + (t3-2*t2+t)*m0 + (-2*t3+3*t2)*y1 + (t3-t2)*m1
+}
@@ -0,0 +1,55 @@
+package loess
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHermiteInterpolation(t *testing.T) {
+	x := make([]float64, 500)
+	pos := make([]float64, len(x))
+	for i := range x {
+		pos[i] = float64(i) * 0.5
+		x[i] = math.Sin(pos[i] / 10)
+	}
+	full, err := Smooth(x, 31, 1, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linear, err := Smooth(x, 31, 10, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hermite, err := Smooth(x, 31, 10, Linear, WithInterpolation(HermiteInterpolation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(x); i += 10 {
+		if hermite[i] != linear[i] {
+			t.Fatalf("Expected the smoothed points to be the same. Got %v and %v at %d", linear[i], hermite[i], i)
+		}
+	}
+	// the full smoothing itself has a kink where the windows stop moving, so only the interior is compared
+	in := func(a []float64) []float64 { return a[31 : len(a)-31] }
+	dl, dh := maxAbsDiff(in(full), in(linear)), maxAbsDiff(in(full), in(hermite))
+	if dh >= dl/10 {
+		t.Errorf("Expected Hermite interpolation (%v) to be much closer to the full smoothing than linear interpolation (%v)", dh, dl)
+	}
+
+	full, err = SmoothPositions(pos, x, 31, 1, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewWithPositions(31, pos, x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetInterpolation(HermiteInterpolation)
+	hermite, err = UnsafeSmooth(s, 31, 10, Linear, make([]float64, len(x)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxAbsDiff(in(full), in(hermite)); d >= dl/10 {
+		t.Errorf("Expected Hermite interpolation with positions to be close to the full smoothing. Max difference: %v", d)
+	}
+}
@@ -0,0 +1,220 @@
+package loess
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// kdLeafSize is the largest number of points in a leaf of a k-d tree.
+const kdLeafSize = 8
+
+// kdTree is a k-d tree over a set of points, used to find the nearest neighbours of a query point.
+//
+// Each node is a cell: an axis aligned box, split at the median of the points in the cell along the dimension in which they are most spread out.
+type kdTree struct {
+	pts      [][]float64
+	idx      []int // permutation of the points. Each node owns a contiguous range of it
+	leafSize int
+	root     *kdNode
+}
+
+type kdNode struct {
+	lo, hi     []float64 // bounds of the cell
+	start, end int       // the points of the cell are idx[start:end]
+
+	// for internal nodes
+	dim         int
+	split       float64
+	left, right *kdNode
+
+	// for leaves of a vertex tree: the vertices at the corners of the cell. Bit k of the index of a corner is set if it is at hi[k]
+	vertices []int
+}
+
+func (n *kdNode) leaf() bool { return n.left == nil }
+
+// contains checks if q is in the cell.
+func (n *kdNode) contains(q []float64) bool {
+	for k, v := range q {
+		if v < n.lo[k] || v > n.hi[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the leaf cell containing q, or nil if q is outside of the root cell.
+func (t *kdTree) find(q []float64) *kdNode {
+	n := t.root
+	if !n.contains(q) {
+		return nil
+	}
+	for !n.leaf() {
+		if q[n.dim] < n.split {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return n
+}
+
+// leaves calls fn on every leaf cell of the tree.
+func (t *kdTree) leaves(fn func(*kdNode)) {
+	var walk func(n *kdNode)
+	walk = func(n *kdNode) {
+		if n.leaf() {
+			fn(n)
+			return
+		}
+		walk(n.left)
+		walk(n.right)
+	}
+	walk(t.root)
+}
+
+// newKDTree builds a k-d tree over the points, whose cells hold at most leafSize points.
+// The root cell is the bounding box of the points, enlarged by margin times its extent on every side.
+func newKDTree(pts [][]float64, leafSize int, margin float64) *kdTree {
+	if leafSize < 1 {
+		leafSize = 1
+	}
+	t := &kdTree{pts: pts, idx: make([]int, len(pts)), leafSize: leafSize}
+	for i := range t.idx {
+		t.idx[i] = i
+	}
+	dim := len(pts[0])
+	lo, hi := make([]float64, dim), make([]float64, dim)
+	for k := range lo {
+		lo[k], hi[k] = math.Inf(1), math.Inf(-1)
+	}
+	for _, p := range pts {
+		for k, v := range p {
+			lo[k] = math.Min(lo[k], v)
+			hi[k] = math.Max(hi[k], v)
+		}
+	}
+	for k := range lo {
+		m := margin * (hi[k] - lo[k])
+		lo[k] -= m
+		hi[k] += m
+	}
+	t.root = t.build(0, len(pts), lo, hi)
+	return t
+}
+
+func (t *kdTree) build(start, end int, lo, hi []float64) *kdNode {
+	n := &kdNode{lo: lo, hi: hi, start: start, end: end}
+	if end-start <= t.leafSize {
+		return n
+	}
+
+	// split along the dimension in which the points are most spread out
+	idx := t.idx[start:end]
+	var spread float64
+	for k := range lo {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, i := range idx {
+			min = math.Min(min, t.pts[i][k])
+			max = math.Max(max, t.pts[i][k])
+		}
+		if max-min > spread {
+			spread = max - min
+			n.dim = k
+		}
+	}
+	if spread == 0 {
+		// all the points are the same
+		return n
+	}
+	sort.Slice(idx, func(a, b int) bool { return t.pts[idx[a]][n.dim] < t.pts[idx[b]][n.dim] })
+	mid := len(idx) / 2
+	n.split = t.pts[idx[mid]][n.dim]
+
+	leftHi := append([]float64(nil), hi...)
+	leftHi[n.dim] = n.split
+	rightLo := append([]float64(nil), lo...)
+	rightLo[n.dim] = n.split
+	n.left = t.build(start, start+mid, lo, leftHi)
+	n.right = t.build(start+mid, end, rightLo, hi)
+	return n
+}
+
+// neighbour is a point found by a nearest neighbour search, along with its squared distance to the query.
+type neighbour struct {
+	i     int
+	dist2 float64
+}
+
+// neighbours is a max heap of neighbours by distance.
+type neighbours []neighbour
+
+func (h neighbours) Len() int            { return len(h) }
+func (h neighbours) Less(i, j int) bool  { return h[i].dist2 > h[j].dist2 }
+func (h neighbours) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighbours) Push(x interface{}) { *h = append(*h, x.(neighbour)) }
+func (h *neighbours) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// nearest returns the k nearest neighbours of q (in no particular order). buf is reused if it has enough capacity.
+func (t *kdTree) nearest(q []float64, k int, buf neighbours) neighbours {
+	h := buf[:0]
+	t.search(t.root, q, k, &h)
+	return h
+}
+
+func (t *kdTree) search(n *kdNode, q []float64, k int, h *neighbours) {
+	if len(*h) == k && boxDist2(n.lo, n.hi, q) > (*h)[0].dist2 {
+		return
+	}
+	if n.leaf() {
+		for _, i := range t.idx[n.start:n.end] {
+			d := dist2(t.pts[i], q)
+			switch {
+			case len(*h) < k:
+				heap.Push(h, neighbour{i, d})
+			case d < (*h)[0].dist2:
+				(*h)[0] = neighbour{i, d}
+				heap.Fix(h, 0)
+			}
+		}
+		return
+	}
+	// search the side of the split that the query is on first
+	first, second := n.left, n.right
+	if q[n.dim] >= n.split {
+		first, second = second, first
+	}
+	t.search(first, q, k, h)
+	t.search(second, q, k, h)
+}
+
+// boxDist2 is the squared distance from q to the nearest point of the box.
+func boxDist2(lo, hi, q []float64) float64 {
+	var retVal float64
+	for k, v := range q {
+		var d float64
+		switch {
+		case v < lo[k]:
+			d = lo[k] - v
+		case v > hi[k]:
+			d = v - hi[k]
+		}
+		retVal += d * d
+	}
+	return retVal
+}
+
+func dist2(a, b []float64) float64 {
+	var retVal float64
+	for k := range a {
+		d := a[k] - b[k]
+		retVal += d * d
+	}
+	return retVal
+}
@@ -0,0 +1,97 @@
+package loess
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kernel is a weighting function for the neighbours in a window. The zero value is the tricube kernel, which is the LOESS default.
+//
+// All kernels are functions of the scaled distance u = |x - xᵢ| / λ, where λ is the distance from x to the furthest point of the window,
+// and are 0 for u >= 1.
+type Kernel int
+
+const (
+	// Tricube is (1 - u³)³. It has a fast, inlined path.
+	Tricube Kernel = iota
+	// Epanechnikov is 1 - u²
+	Epanechnikov
+	// Gaussian is exp(-u²/(2σ²)) with σ = 1/3, truncated at u = 1 (three standard deviations).
+	Gaussian
+	// Biweight (also known as bisquare) is (1 - u²)²
+	Biweight
+	// Uniform gives equal weight to all points in the window.
+	Uniform
+)
+
+// Weight returns the weight of a point at scaled distance u.
+func (k Kernel) Weight(u float64) float64 {
+	u = math.Abs(u)
+	if u >= 1 {
+		return 0
+	}
+	switch k {
+	case Tricube:
+		return tricube(u)
+	case Epanechnikov:
+		return 1 - u*u
+	case Gaussian:
+		return math.Exp(-4.5 * u * u)
+	case Biweight:
+		v := 1 - u*u
+		return v * v
+	case Uniform:
+		return 1
+	}
+	panic(fmt.Sprintf("Unknown kernel %d", int(k)))
+}
+
+func (k Kernel) String() string {
+	switch k {
+	case Tricube:
+		return "Tricube"
+	case Epanechnikov:
+		return "Epanechnikov"
+	case Gaussian:
+		return "Gaussian"
+	case Biweight:
+		return "Biweight"
+	case Uniform:
+		return "Uniform"
+	}
+	return fmt.Sprintf("Kernel(%d)", int(k))
+}
+
+// kernelWeights computes the local weights with a kernel other than the tricube kernel. See localWeights.
+func (s *State) kernelWeights(x float64, left, right int, lambda float64) error {
+	ceil := 0.99999 * lambda
+	flor := 0.00001 * lambda
+
+	var sum float64
+	W := s.w
+	E := s.e
+	for j := left; j <= right; j++ {
+		delta := math.Abs(x - s.At(j))
+		var w float64
+		if delta <= ceil {
+			if delta <= flor {
+				w = 1
+			} else {
+				w = s.kernel.Weight(delta / lambda)
+			}
+			if len(E) > j {
+				w *= E[j]
+			}
+			sum += w
+		}
+		W[j] = w
+	}
+
+	if sum <= 0 {
+		return errTotal
+	}
+	for j := left; j <= right; j++ {
+		W[j] /= sum
+	}
+	return nil
+}
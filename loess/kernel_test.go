@@ -0,0 +1,86 @@
+package loess
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/dawson"
+)
+
+func TestKernel(t *testing.T) {
+	kernels := []Kernel{Tricube, Epanechnikov, Gaussian, Biweight, Uniform}
+	for _, k := range kernels {
+		if k.Weight(0) != 1 {
+			t.Errorf("%v: expected a weight of 1 at 0. Got %v", k, k.Weight(0))
+		}
+		if k.Weight(1) != 0 || k.Weight(-1.5) != 0 {
+			t.Errorf("%v: expected a weight of 0 outside the window", k)
+		}
+		// non-increasing in u
+		prev := 1.0
+		for u := 0.05; u < 1; u += 0.05 {
+			w := k.Weight(u)
+			if w > prev || w < 0 {
+				t.Errorf("%v: weight %v at %v is not within [0, %v]", k, w, u, prev)
+			}
+			prev = w
+		}
+	}
+	if !dawson.CloseF64(Tricube.Weight(0.5), tricube(0.5)) {
+		t.Errorf("Expected the tricube kernel to be the tricube function")
+	}
+}
+
+func TestSmoothKernel(t *testing.T) {
+	a := []float64{5, 6.0, 2.0, 4.5, 5, 5, 6.5, 3.5, 4.0, 5, 5, 5.5, 3.5, 5.0, 5}
+	tricubed, _ := Smooth(a, 5, 1, Linear)
+
+	// the generic path with the tricube kernel is the same as the inlined path.
+	s := New(5, a)
+	generic := make([]float64, len(a))
+	for i := range a {
+		left, right := s.Bounds(i)
+		lambda := math.Max(float64(i-left), float64(right-i))
+		if err := s.kernelWeights(float64(i), left, right, lambda); err != nil {
+			t.Fatal(err)
+		}
+		if err := Linear(s, float64(i), float64(left), float64(right)); err != nil {
+			t.Fatal(err)
+		}
+		for j := left; j <= right; j++ {
+			generic[i] += s.w[j] * a[j]
+		}
+	}
+	if !dawson.AllClose(tricubed, generic) {
+		t.Errorf("Expected %v. Got %v", tricubed, generic)
+	}
+
+	for _, k := range []Kernel{Epanechnikov, Gaussian, Biweight, Uniform} {
+		smoothed, err := Smooth(a, 5, 1, Linear, WithKernel(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dawson.AllClose(smoothed, tricubed) {
+			t.Errorf("%v: expected a different result from the tricube kernel", k)
+		}
+
+		s := New(5, a)
+		s.SetKernel(k)
+		unsafe, err := UnsafeSmooth(s, 5, 1, Linear, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !dawson.AllClose(smoothed, unsafe) {
+			t.Errorf("%v: expected the state's kernel to be used", k)
+		}
+	}
+
+	// the kernel option does not change the kernel of the state
+	s = New(5, a)
+	if _, err := UnsafeSmooth(s, 5, 1, Linear, nil, WithKernel(Uniform)); err != nil {
+		t.Fatal(err)
+	}
+	if s.kernel != Tricube {
+		t.Errorf("Expected the state's kernel to be restored")
+	}
+}
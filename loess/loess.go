@@ -2,13 +2,31 @@ package loess
 
 import (
 	"math"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 var errTotal = errors.New("Total <= 0")
 
-// WeightUpdate is a function that modifies the State.
+// WeightUpdate is a function that modifies the State. It performs the regression part of a local regression.
+//
+// Custom local regressions may be implemented outside of this package. A WeightUpdate is called by Regress (and hence
+// by Smooth, Predict, etc) after the local weights of the window have been computed, and must abide by the following contract:
+//
+//   - x is the position being regressed. left and right are the (inclusive) indices of the window in the data.
+//   - On entry, W()[left:right+1] holds the kernel weights of the window (see Kernel), multiplied by the external weights E() if any,
+//     and normalized to sum to 1. At(j) is the position of the jth datum.
+//   - On return, W()[left:right+1] must hold the row of the operator matrix at x: the fitted value is the sum of W()[j]*X()[j] over the window.
+//   - Only W()[left:right+1] may be modified, and the state and its slices must not be retained after returning.
+//   - Returning an error indicates that the regression failed. Smooth then uses the value of the datum itself.
+//
+// Because the operator row is returned rather than the fitted value, custom regressions work with everything that works with
+// operator rows: fit statistics (SmoothFit), bandwidth selection (SelectWidth), and the like.
+//
+// The accessors W, X and E have value receivers, as they always have, so that existing code that calls them on a State value keeps working.
+// Each call copies the State, so prefer the pointer the WeightUpdate is given. Every other method, Pos and Width included, has a pointer receiver.
 type WeightUpdate func(s *State, x, left, right float64) error
 
 // State represents a state for regression. Every field will be mutated by the Regress and Smooth functions
@@ -17,7 +35,11 @@ type State struct {
 	w     []float64 // weights
 	x     []float64 // data
 	e     []float64 // external weights
+	pos   []float64 // positions of the data. If nil, the data is at positions 0..n-1
 
+	// the kernel, fast path and interpolation of the state. Smooth's options are applied to it for the duration of a call,
+	// which spares allocating them.
+	options
 }
 
 // New creates a new LOESS state
@@ -39,15 +61,130 @@ func NewWithExternal(width int, x, e []float64) *State {
 	}
 }
 
+// NewWithPositions creates a new LOESS state for data observed at the given positions. The positions must be sorted in ascending order.
+// The external weights are optional, and may be nil.
+//
+// Windows are then formed from the width nearest neighbours (by position) of the point being regressed, instead of by index.
+func NewWithPositions(width int, pos, x, e []float64) (*State, error) {
+	if len(pos) != len(x) {
+		return nil, errors.Errorf("Expected %d positions. Got %d", len(x), len(pos))
+	}
+	if !sort.Float64sAreSorted(pos) {
+		return nil, errors.New("Positions must be sorted in ascending order")
+	}
+	return &State{
+		width: width,
+		w:     make([]float64, len(x)),
+		x:     x,
+		e:     e,
+		pos:   pos,
+	}, nil
+}
+
+// NewWithTimes creates a new LOESS state for data observed at the given times, which must be sorted in ascending order.
+// The positions are measured in seconds since the first time, so query points passed to Predict and Evaluate must be too.
+func NewWithTimes(width int, ts []time.Time, x, e []float64) (*State, error) {
+	pos := make([]float64, len(ts))
+	for i := range ts {
+		pos[i] = ts[i].Sub(ts[0]).Seconds()
+	}
+	return NewWithPositions(width, pos, x, e)
+}
+
 // W returns the weights in the state
 func (s State) W() []float64 { return s.w }
 
 // X returns the Xs in the state.
 func (s State) X() []float64 { return s.x }
 
-// W returns the externally defined weights in the state.
+// E returns the externally defined weights in the state.
 func (s State) E() []float64 { return s.e }
 
+// Pos returns the positions of the data in the state. It is nil if the data is at positions 0..n-1.
+func (s *State) Pos() []float64 { return s.pos }
+
+// Width returns the width of the windows (the number of nearest neighbours) of the state.
+func (s *State) Width() int { return s.width }
+
+// Kernel returns the kernel used to weigh the neighbours of a point.
+func (s *State) Kernel() Kernel { return s.kernel }
+
+// SetKernel sets the kernel used to weigh the neighbours of a point. The default is Tricube.
+func (s *State) SetKernel(k Kernel) { s.kernel = k }
+
+// At returns the position of the jth datum. This is j itself if the state has no positions.
+func (s *State) At(j int) float64 {
+	if s.pos == nil {
+		return float64(j)
+	}
+	return s.pos[j]
+}
+
+// Window returns the indices of the left and right bounds of the width nearest neighbours of q in pos, which must be sorted in ascending order.
+func Window(pos []float64, q float64, width int) (left, right int) {
+	n := len(pos)
+	if width >= n {
+		return 0, n - 1
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	// binary search for the leftmost bound such that the window [left, left+width) is the nearest
+	lo, hi := 0, n-width
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if q-pos[mid] > pos[mid+width]-q {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo + width - 1
+}
+
+// Predict evaluates the local regression at an arbitrary query point q.
+// If the state has positions, q is a position, otherwise q is a (possibly fractional) index.
+func Predict(s *State, fn WeightUpdate, q float64) (float64, error) {
+	var left, right int
+	if s.pos != nil {
+		left, right = Window(s.pos, q, s.width)
+	} else {
+		left, right = indexWindow(q, len(s.x), s.width)
+	}
+	return Regress(s, fn, q, float64(left), float64(right))
+}
+
+// Evaluate evaluates the local regression at each of the query points. See Predict for details.
+func Evaluate(s *State, fn WeightUpdate, qs []float64) ([]float64, error) {
+	retVal := make([]float64, len(qs))
+	for i, q := range qs {
+		var err error
+		if retVal[i], err = Predict(s, fn, q); err != nil {
+			return nil, errors.Wrapf(err, "Failed to evaluate at %v", q)
+		}
+	}
+	return retVal, nil
+}
+
+// indexWindow is the nearest neighbour window for data at positions 0..n-1.
+func indexWindow(q float64, n, width int) (left, right int) {
+	if width >= n {
+		return 0, n - 1
+	}
+	if width < 1 {
+		width = 1
+	}
+	left = int(math.Ceil(q - float64(width)/2))
+	switch {
+	case left < 0:
+		left = 0
+	case left > n-width:
+		left = n - width
+	}
+	return left, left + width - 1
+}
+
 // Regress performs local regression of x between left and right
 func Regress(s *State, fn WeightUpdate, x, left, right float64) (retVal float64, err error) {
 	if err = s.localWeights(x, left, right); err != nil {
@@ -66,17 +203,17 @@ func Regress(s *State, fn WeightUpdate, x, left, right float64) (retVal float64,
 }
 
 // Smooth smooths a slice of float64, with the provided width, jumps and update functions
-func Smooth(x []float64, width, jump int, fn WeightUpdate) ([]float64, error) {
+func Smooth(x []float64, width, jump int, fn WeightUpdate, opts ...Option) ([]float64, error) {
 	if jump <= 0 {
 		return nil, errors.Errorf("Cannot work with jump == 0")
 	}
 	s := New(width, x)
 	retVal := make([]float64, len(x))
-	return smooth(s, width, jump, fn, retVal), nil
+	return s.smoothWith(width, jump, fn, retVal, opts)
 }
 
 // UnsafeSmooth is like Smooth, except the state is passed in, as well as a optional return value to be mutated.
-func UnsafeSmooth(regression *State, width, jump int, fn WeightUpdate, retVal []float64) ([]float64, error) {
+func UnsafeSmooth(regression *State, width, jump int, fn WeightUpdate, retVal []float64, opts ...Option) ([]float64, error) {
 	if regression.width != width {
 		return nil, errors.Errorf("Regression width: %d. Smoothing width %d", regression.width, width)
 	}
@@ -91,7 +228,64 @@ func UnsafeSmooth(regression *State, width, jump int, fn WeightUpdate, retVal []
 		return nil, errors.Errorf("Expected the preallocated value to have at least %d elements. Got %d elements.", len(regression.x), len(retVal))
 	}
 
-	return smooth(regression, width, jump, fn, retVal), nil
+	return regression.smoothWith(width, jump, fn, retVal, opts)
+}
+
+// SmoothPositions smooths data observed at the given positions (which must be sorted in ascending order).
+// The windows are formed from the width nearest neighbours of each position.
+func SmoothPositions(pos, x []float64, width, jump int, fn WeightUpdate) ([]float64, error) {
+	if jump <= 0 {
+		return nil, errors.Errorf("Cannot work with jump == 0")
+	}
+	s, err := NewWithPositions(width, pos, x, nil)
+	if err != nil {
+		return nil, err
+	}
+	retVal := make([]float64, len(x))
+	return smoothPositions(s, jump, fn, retVal), nil
+}
+
+// smoothPositions is the equivalent of smooth for states with positions. Skipped points are linearly interpolated by position.
+func smoothPositions(s *State, jump int, fn WeightUpdate, retVal []float64) []float64 {
+	x, pos := s.x, s.pos
+	size := len(x)
+	if size == 1 {
+		retVal[0] = x[0]
+		if s.slopes != nil {
+			s.slopes[0] = 0
+		}
+		return retVal
+	}
+
+	slopes, hermite := s.slopeBuffers(size, jump)
+	last := 0
+	for i := 0; ; i += jump {
+		if i >= size {
+			i = size - 1
+		}
+		if slopes != nil {
+			left, right := Window(pos, pos[i], s.width)
+			slopes[i] = s.slope(pos[i], float64(left), float64(right))
+		}
+		if point, err := Predict(s, fn, pos[i]); err == nil {
+			retVal[i] = point
+		} else {
+			retVal[i] = x[i]
+		}
+
+		// interpolate between the previously smoothed point and this one
+		if i-last > 1 {
+			s.interpolate(retVal, hermite, last, i)
+			if s.slopes != nil {
+				s.interpolate(s.slopes, nil, last, i)
+			}
+		}
+		last = i
+		if i == size-1 {
+			break
+		}
+	}
+	return retVal
 }
 
 func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []float64 {
@@ -99,11 +293,16 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 	size := len(x)
 	if size == 1 {
 		retVal[0] = x[0]
+		if s.slopes != nil {
+			s.slopes[0] = 0
+		}
 		return retVal
 	}
 
 	left, right := -1.0, -1.0
 	half := (width + 1) / 2
+	Regress := s.fastRegress(width, half, fn)
+	slopes, hermite := s.slopeBuffers(size, jump)
 	switch {
 	case width >= size:
 		left = 0
@@ -111,6 +310,9 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 
 		for i := 0; i < size; i += jump {
 			j := float64(i)
+			if slopes != nil {
+				slopes[i] = s.slope(j, left, right)
+			}
 			if point, err := Regress(s, fn, j, left, right); err == nil {
 				retVal[i] = point
 			} else {
@@ -129,6 +331,9 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 				left++
 				right++
 			}
+			if slopes != nil {
+				slopes[i] = s.slope(j, left, right)
+			}
 			if point, err := Regress(s, fn, j, left, right); err == nil {
 				retVal[i] = point
 			} else {
@@ -148,6 +353,9 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 			}
 
 			right = left + float64(width-1)
+			if slopes != nil {
+				slopes[i] = s.slope(j, left, right)
+			}
 			if point, err := Regress(s, fn, j, left, right); err == nil {
 				retVal[i] = point
 			} else {
@@ -158,16 +366,18 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 	}
 
 	if jump != 1 {
-		jmp := float64(jump)
 		for i := 0; i < size-jump; i += jump {
-			slope := (retVal[i+jump] - retVal[i]) / jmp
-			for j := i + 1; j < i+jump; j++ {
-				retVal[j] = retVal[i] + slope*float64(j-i)
+			s.interpolate(retVal, hermite, i, i+jump)
+			if s.slopes != nil {
+				s.interpolate(s.slopes, nil, i, i+jump)
 			}
 		}
 		last := size - 1
 		lastSmoothedPos := (last / jump) * jump
 		if lastSmoothedPos != last {
+			if slopes != nil {
+				slopes[last] = s.slope(float64(last), left, right)
+			}
 			if point, err := Regress(s, fn, float64(last), left, right); err == nil {
 				retVal[last] = point
 			} else {
@@ -175,27 +385,33 @@ func smooth(s *State, width, jump int, fn WeightUpdate, retVal []float64) []floa
 			}
 
 			if lastSmoothedPos != last-1 {
-				slope := (retVal[last] - retVal[lastSmoothedPos]) / float64(last-lastSmoothedPos)
-				for j := lastSmoothedPos + 1; j < last; j++ {
-					retVal[j] = retVal[lastSmoothedPos] + slope*float64(j-lastSmoothedPos)
+				s.interpolate(retVal, hermite, lastSmoothedPos, last)
+				if s.slopes != nil {
+					s.interpolate(s.slopes, nil, lastSmoothedPos, last)
 				}
-
 			}
 		}
 	}
 	return retVal
 }
 
+// LocalWeights computes the normalized kernel weights of the window [left, right] for a regression at x, which are stored in W().
+// This is what Regress does before calling a WeightUpdate.
+func (s *State) LocalWeights(x, left, right float64) error { return s.localWeights(x, left, right) }
+
 // Local Weights perform local weight smoothing via the tricube function
 // left >= 1
 func (s *State) localWeights(x, left, right float64) error {
+	if s.pos != nil {
+		return s.localWeightsPositions(x, int(left), int(right))
+	}
 	lambda := math.Max(x-left, right-x)
 
 	// lambda is adjusted to conform to roughly width /2
 	//
 	// However the netlib fortran code seems to indicate that it's lambda = (width-n) / 2
 	// After checking around, it seems that most implementations use something like the below instead
-	X := s.X()
+	X := s.x
 	if s.width > len(X) {
 		lambda += float64(s.width-len(X)) / 2.0
 	}
@@ -203,6 +419,9 @@ func (s *State) localWeights(x, left, right float64) error {
 	if lambda <= 0 {
 		return errors.Errorf("Lambda %v", lambda)
 	}
+	if s.kernel != Tricube {
+		return s.kernelWeights(x, int(left), int(right), lambda)
+	}
 
 	// Numerical stabilization
 	ceil := 0.99999 * lambda
@@ -251,6 +470,59 @@ func (s *State) localWeights(x, left, right float64) error {
 	return nil
 }
 
+// localWeightsPositions is localWeights for data with explicit positions. left and right are indices into the data.
+func (s *State) localWeightsPositions(x float64, left, right int) error {
+	pos := s.pos
+	lambda := math.Max(x-pos[left], pos[right]-x)
+
+	// see localWeights. The adjustment is scaled by the mean spacing of the positions
+	n := len(pos)
+	if s.width > n && n > 1 {
+		spacing := (pos[n-1] - pos[0]) / float64(n-1)
+		lambda += float64(s.width-n) / 2.0 * spacing
+	}
+
+	if lambda <= 0 {
+		return errors.Errorf("Lambda %v", lambda)
+	}
+	if s.kernel != Tricube {
+		return s.kernelWeights(x, left, right, lambda)
+	}
+
+	ceil := 0.99999 * lambda
+	flor := 0.00001 * lambda
+
+	var sum float64
+	W := s.w
+	E := s.e
+	for j := left; j <= right; j++ {
+		delta := math.Abs(x - pos[j])
+		var w float64
+		if delta <= ceil {
+			if delta <= flor {
+				w = 1
+			} else {
+				frac := delta / lambda
+				trix := 1.0 - frac*frac*frac
+				w = trix * trix * trix
+			}
+			if len(E) > j {
+				w *= E[j]
+			}
+			sum += w
+		}
+		W[j] = w
+	}
+
+	if sum <= 0 {
+		return errTotal
+	}
+	for j := left; j <= right; j++ {
+		W[j] /= sum
+	}
+	return nil
+}
+
 // tricbue is the tricube function.
 func tricube(u float64) float64 {
 	if u >= 1 {
@@ -277,6 +549,9 @@ func Linear(s *State, x, left, right float64) error {
 	// Additionally, this code is quite unrolled and quite difficult to understand
 	// This was done for performance purposes.
 	// I will have tried to explain the equivalence in the comments
+	if s.pos != nil {
+		return linearPositions(s, x, int(left), int(right))
+	}
 	W := s.w // s.W()
 	X := s.x // s.X()
 
@@ -307,58 +582,33 @@ func Linear(s *State, x, left, right float64) error {
 	return nil
 }
 
-// Quadratic performs quadratic regression, constrained to left. and right.
-func Quadratic(s *State, x, left, right float64) error {
-	// Notes for implementors of other variations of the regression -
-	// you can access weights and Xs of the state by calling the function.
-	//
-	// Additionally, this code is quite unrolled and quite difficult to understand
-	// This was done for performance purposes.
-	// I will have tried to explain the equivalence in the comments
-	W := s.w                   // s.W()
-	X := s.x                   // s.X()
-	var x1, x2, x3, x4 float64 // unrolled calculation of means m2, m3, m4
-
-	for i := left; i <= right; i++ {
-		j := int(i)
-		w := W[j]
-		x1w := i * w
-		x2w := i * x1w
-		x3w := i * x2w
-		x4w := i * x3w
+// linearPositions is Linear for data with explicit positions.
+func linearPositions(s *State, x float64, left, right int) error {
+	W := s.w
+	pos := s.pos
 
-		x1 += x1w
-		x2 += x2w
-		x3 += x3w
-		x4 += x4w
+	var mean, variance float64
+	for j := left; j <= right; j++ {
+		mean += pos[j] * W[j]
+	}
+	for j := left; j <= right; j++ {
+		delta := pos[j] - mean
+		variance += W[j] * delta * delta
 	}
 
-	m2 := x2 - x1*x1
-	m3 := x3 - x2*x1
-	m4 := x4 - x2*x2
-
-	variance := m2*m4 - m3*m3
-
-	// like in the linear function, we restrict gradient updates
-	// to points that are spread out enough.
-
-	r := float64(len(X) - 1)
+	r := pos[len(pos)-1] - pos[0]
 	thresh := (0.01 * r)
-	for variance > thresh*thresh {
-		beta4 := m2 / variance
-		beta3 := m3 / variance
-		beta2 := m4 / variance
-
-		x1 := x - x1
-		x2 := x*x - x2
-
-		a1 := beta2*x1 - beta3*x2
-		a2 := beta4*x2 - beta3*x1
-
-		for i := left; i <= right; i++ {
-			j := int(i)
-			W[j] *= (1 + a1*(i-x1) + a2*(i*i-x2))
+	if variance >= thresh*thresh {
+		beta := (x - mean) / variance
+		for j := left; j <= right; j++ {
+			W[j] *= 1.0 + beta*(pos[j]-mean)
 		}
 	}
 	return nil
 }
+
+// Quadratic performs quadratic regression, constrained to left. and right.
+func Quadratic(s *State, x, left, right float64) error {
+	polynomial(s, 2, x, int(left), int(right))
+	return nil
+}
@@ -129,3 +129,80 @@ func Example_externalWeights() {
 	// Output:
 	// Smoothed [5.00 3.50 2.00 3.50 5.00 5.00 4.25 3.50 4.25 5.00 5.00 4.25 3.50 4.25 5.00 5.00 3.75 2.50 3.75 5.00]
 }
+
+func TestWindow(t *testing.T) {
+	pos := []float64{0, 1, 1.5, 4, 4.2, 4.3, 9, 10}
+	cases := []struct {
+		q           float64
+		width       int
+		left, right int
+	}{
+		{-1, 3, 0, 2},
+		{1.4, 3, 0, 2},
+		{4.1, 3, 3, 5},
+		{8, 2, 6, 7},
+		{20, 3, 5, 7},
+		{4, 20, 0, 7},
+	}
+	for i, c := range cases {
+		l, r := Window(pos, c.q, c.width)
+		if l != c.left || r != c.right {
+			t.Errorf("Case %d: expected [%d, %d]. Got [%d, %d]", i, c.left, c.right, l, r)
+		}
+	}
+}
+
+func TestSmoothPositions(t *testing.T) {
+	// regularly spaced positions should give the same results as Smooth
+	a := []float64{5, 6.0, 2.0, 4.5, 5, 5, 6.5, 3.5, 4.0, 5, 5, 5.5, 3.5, 5.0, 5}
+	pos := make([]float64, len(a))
+	for i := range pos {
+		pos[i] = float64(i)
+	}
+	for _, width := range []int{3, 5, 7, 21} {
+		b1, err := Smooth(a, width, 1, Linear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b2, err := SmoothPositions(pos, a, width, 1, Linear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !dawson.AllClose(b1, b2) {
+			t.Errorf("Width %d: expected %v. Got %v", width, b1, b2)
+		}
+	}
+
+	// local linear regression reproduces a line exactly, wherever the points are
+	pos = []float64{0, 0.1, 0.5, 2, 2.2, 3.7, 5, 5.1, 8, 9.5}
+	x := make([]float64, len(pos))
+	for i := range pos {
+		x[i] = 2*pos[i] + 1
+	}
+	s, err := NewWithPositions(4, pos, x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qs := []float64{0.3, 2.1, 4, 7.77, 9.5}
+	ys, err := Evaluate(s, Linear, qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, q := range qs {
+		if !dawson.CloseF64(ys[i], 2*q+1) {
+			t.Errorf("Evaluated at %v: expected %v. Got %v", q, 2*q+1, ys[i])
+		}
+	}
+
+	smoothed, err := UnsafeSmooth(s, 4, 3, Linear, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dawson.AllClose(smoothed, x) {
+		t.Errorf("Expected %v. Got %v", x, smoothed)
+	}
+
+	if _, err := NewWithPositions(4, []float64{1, 0}, []float64{1, 2}, nil); err == nil {
+		t.Errorf("Expected an error for unsorted positions")
+	}
+}
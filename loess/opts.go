@@ -0,0 +1,165 @@
+package loess
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Option is an option for Smooth and UnsafeSmooth.
+type Option func(*options)
+
+type options struct {
+	robustIter int
+	kernel     Kernel
+	fast       bool
+	interp     Interpolation
+	slopes     []float64 // see WithSlopes
+}
+
+// WithRobustIter performs n robustness iterations after the initial smoothing (Cleveland's LOWESS).
+// In each iteration, the points are reweighted by the bisquare function of their residuals, scaled by six times the median absolute residual,
+// and the data is smoothed again. Outliers thus have little to no effect on the smoothed values.
+//
+// The robustness weights are multiplied with the external weights of the state, if any. The external weights are restored after smoothing.
+func WithRobustIter(n int) Option {
+	return func(o *options) {
+		o.robustIter = n
+	}
+}
+
+// WithKernel smooths with the given kernel, instead of the kernel of the state. The kernel of the state is restored after smoothing.
+func WithKernel(k Kernel) Option {
+	return func(o *options) {
+		o.kernel = k
+	}
+}
+
+// WithInterpolation fills in the points skipped between jumps with the given interpolation, instead of that of the state.
+// The interpolation of the state is restored after smoothing.
+func WithInterpolation(m Interpolation) Option {
+	return func(o *options) {
+		o.interp = m
+	}
+}
+
+// WithFast smooths with local linear (Linear) or local constant (Constant) regression in O(n log N + width²) time rather than O(n·width),
+// which pays off for long series with wide windows. Other regressions, and states with positions, are smoothed as usual.
+//
+// Every interior window of uniformly spaced data shares the same kernel weights, so the weighted moments that the regression needs
+// are convolutions of the data (and the external weights) with the kernel, which are computed by FFT, in blocks of N points:
+// the power of 2 that is at least max(2·width, 4096). Besides the result, the fast path uses O(N) memory - under 1 MB for windows of up to 2048 points.
+// The windows at the edges, and windows whose total weight is less than 1e-8 of the kernel's, are regressed exactly.
+//
+// The fast path is exact but for rounding: the rounding error of the FFT grows with the norm of a block rather than of a window.
+// Each smoothed value is within about 1e-15·log₂(N)·√N·max|x - x̄| of the exact path, where x̄ is the (weighted) mean of the data -
+// about 1e-12·max|x - x̄| for windows of up to 2048 points. A point whose local variance is within that of the threshold below which
+// Linear falls back to the weighted mean may take the other branch.
+//
+// Because every point is computed by the convolution, jump does not reduce the work of the fast path.
+// If the data of the state is also the output (smoothing in place), the fast path smooths the data as it was before the call,
+// whereas the exact path reads the values it has already overwritten, so the two differ.
+func WithFast() Option {
+	return func(o *options) {
+		o.fast = true
+	}
+}
+
+// WithSlopes also estimates the local slope of every point, which is written into slopes. slopes must have as many elements as the data.
+//
+// The slope of each smoothed point is that of a local linear regression (see RegressDerivatives) on the same window, with the same kernel
+// and weights, as its smoothed value, so it is the slope of the smoothed value when fn is Linear. The slopes of the points skipped
+// between jumps are interpolated linearly. With robustness iterations, the slopes are those of the last iteration.
+// The slopes are always regressed exactly, so they do not benefit from the fast path (see WithFast).
+func WithSlopes(slopes []float64) Option {
+	return func(o *options) {
+		o.slopes = slopes
+	}
+}
+
+// smoothWith smooths the state with the options. The options of the state are restored afterwards.
+func (s *State) smoothWith(width, jump int, fn WeightUpdate, retVal []float64, opts []Option) ([]float64, error) {
+	if len(opts) == 0 {
+		return s.smoothOnce(width, jump, fn, retVal), nil
+	}
+	defer func(o options) { s.options = o }(s.options)
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	if s.slopes != nil && len(s.slopes) < len(s.x) {
+		return nil, errors.Errorf("Expected the slopes to have at least %d elements. Got %d elements.", len(s.x), len(s.slopes))
+	}
+
+	retVal = s.smoothOnce(width, jump, fn, retVal)
+	if s.robustIter <= 0 {
+		return retVal, nil
+	}
+
+	external := s.e
+	defer func() { s.e = external }()
+	robust := make([]float64, len(s.x))
+	combined := make([]float64, len(s.x))
+	for i := 0; i < s.robustIter; i++ {
+		if !bisquareWeights(s.x, retVal, robust) {
+			// the residuals are negligible. Further iterations would not change anything.
+			break
+		}
+		for j := range combined {
+			combined[j] = robust[j]
+			if len(external) > j {
+				combined[j] *= external[j]
+			}
+		}
+		s.e = combined
+		retVal = s.smoothOnce(width, jump, fn, retVal)
+	}
+	return retVal, nil
+}
+
+// smoothOnce smooths the state into retVal, without robustness iterations.
+func (s *State) smoothOnce(width, jump int, fn WeightUpdate, retVal []float64) []float64 {
+	if s.pos != nil {
+		return smoothPositions(s, jump, fn, retVal)
+	}
+	if s.fast && len(retVal) > 0 && len(s.x) > 0 && &retVal[0] == &s.x[0] {
+		// the fast path reads all of the data before anything is written, so smoothing in place is done out of place,
+		// lest the windows at the edges read values that have already been overwritten.
+		copy(retVal, smooth(s, width, jump, fn, make([]float64, len(retVal))))
+		return retVal
+	}
+	return smooth(s, width, jump, fn, retVal)
+}
+
+// bisquareWeights computes the robustness weights from the residuals of the fit.
+// It returns false if the median absolute residual is 0, in which case the weights are not computed.
+func bisquareWeights(x, fitted, weights []float64) bool {
+	for i := range x {
+		weights[i] = math.Abs(x[i] - fitted[i])
+	}
+	sort.Float64s(weights)
+	n := len(weights)
+	med := (weights[(n-1)/2] + weights[n/2]) / 2
+	mad6 := 6 * med
+	if mad6 <= 0 {
+		return false
+	}
+
+	// numerical stability, as with the robustness weights in package stl
+	ceil := 0.999 * mad6
+	flor := 0.001 * mad6
+	for i := range x {
+		a := math.Abs(x[i] - fitted[i])
+		switch {
+		case a <= flor:
+			weights[i] = 1
+		case a <= ceil:
+			h := a / mad6
+			w := 1 - h*h
+			weights[i] = w * w
+		default:
+			weights[i] = 0
+		}
+	}
+	return true
+}
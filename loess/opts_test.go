@@ -0,0 +1,101 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRobustSmooth(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	n := 60
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = math.Sin(float64(i)/8) + 0.2*r.NormFloat64()
+	}
+	clean := make([]float64, n)
+	copy(clean, x)
+	x[30] += 5 // outlier
+
+	plain, err := Smooth(x, 21, 1, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	robust, err := Smooth(x, 21, 1, Linear, WithRobustIter(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := Smooth(clean, 21, 1, Linear)
+
+	for _, i := range []int{28, 29, 30, 31, 32} {
+		pe := math.Abs(plain[i] - expected[i])
+		re := math.Abs(robust[i] - expected[i])
+		if re >= pe || re > 0.1 {
+			t.Errorf("At %d: expected the robust smoother to be close to the clean smooth. Plain error %v, robust error %v", i, pe, re)
+		}
+	}
+
+	// external weights are restored
+	e := make([]float64, n)
+	for i := range e {
+		e[i] = 1
+	}
+	s := NewWithExternal(11, x, e)
+	if _, err := UnsafeSmooth(s, 11, 2, Linear, nil, WithRobustIter(2)); err != nil {
+		t.Fatal(err)
+	}
+	if &s.E()[0] != &e[0] {
+		t.Errorf("Expected the external weights to be restored")
+	}
+	for i := range e {
+		if e[i] != 1 {
+			t.Fatalf("Expected the external weights to be unmodified")
+		}
+	}
+
+	// a perfect fit stops early
+	line := make([]float64, n)
+	for i := range line {
+		line[i] = float64(i)
+	}
+	smoothed, _ := Smooth(line, 7, 1, Linear, WithRobustIter(2))
+	for i := range line {
+		if math.Abs(smoothed[i]-line[i]) > 1e-9 {
+			t.Fatalf("Expected a line to be reproduced. Got %v", smoothed)
+		}
+	}
+}
+
+func TestWithSlopes(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	x := make([]float64, 50)
+	for i := range x {
+		x[i] = math.Sin(float64(i)/8) + 0.1*r.NormFloat64()
+	}
+	for _, jump := range []int{1, 3} {
+		for _, opts := range [][]Option{nil, {WithFast()}, {WithInterpolation(HermiteInterpolation)}} {
+			slopes := make([]float64, len(x))
+			smoothed, err := Smooth(x, 11, jump, Linear, append(opts, WithSlopes(slopes))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := Smooth(x, 11, jump, Linear, opts...)
+			d, err := SmoothDerivatives(x, 11, jump, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range x {
+				if smoothed[i] != expected[i] {
+					t.Fatalf("Jump %d: expected the slopes not to change the smoothed values", jump)
+				}
+				if math.Abs(slopes[i]-d.Slope[i]) > 1e-12 {
+					t.Errorf("Jump %d, %d options: expected the slope at %d to be %v. Got %v", jump, len(opts), i, d.Slope[i], slopes[i])
+				}
+			}
+		}
+	}
+
+	if _, err := Smooth(x, 11, 1, Linear, WithSlopes(make([]float64, 3))); err == nil {
+		t.Errorf("Expected an error for too few slopes")
+	}
+}
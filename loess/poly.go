@@ -0,0 +1,155 @@
+package loess
+
+import (
+	"fmt"
+	"math"
+)
+
+// rankTol is the relative tolerance below which a local design matrix is considered rank deficient.
+const rankTol = 1e-10
+
+// Constant performs local constant regression (the Nadaraya-Watson estimator): the fitted value is the kernel weighted mean of the window.
+// The weights computed by the kernel are therefore left as they are.
+func Constant(s *State, x, left, right float64) error { return nil }
+
+// Polynomial returns a WeightUpdate that performs local polynomial regression of the given degree, by weighted least squares.
+//
+// Degrees 0, 1 and 2 return Constant, Linear and Quadratic respectively - Linear is a hand-unrolled fast path.
+// Higher degrees solve the weighted least squares problem by a QR decomposition of the local design matrix.
+// If there are too few distinct points in the window for the degree, the degree is lowered until the problem is solvable.
+//
+// Smooth reuses the operator row of the interior windows of Constant, Linear and Quadratic (and convolves Constant and Linear, see WithFast),
+// but it only recognises those top level functions, so higher degrees are smoothed as custom regressions are, with every window regressed on its own.
+func Polynomial(degree int) WeightUpdate {
+	switch {
+	case degree < 0:
+		panic(fmt.Sprintf("Cannot perform local polynomial regression of degree %d", degree))
+	case degree == 0:
+		return Constant
+	case degree == 1:
+		return Linear
+	case degree == 2:
+		return Quadratic
+	}
+	return func(s *State, x, left, right float64) error {
+		polynomial(s, degree, x, int(left), int(right))
+		return nil
+	}
+}
+
+// polynomial updates the weights of the window such that Σ W[j] X[j] is the value at x of the weighted least squares polynomial fit.
+//
+// With the design matrix A (rows [1, u, u², ...] of the scaled coordinates u = (xⱼ - x) / scale) and the kernel weights w,
+// the fitted value at x is e₁'(A'WA)⁻¹A'W X, so the new weight of the jth point is wⱼ aⱼ'β, where A'WA β = e₁.
+// With the QR decomposition of √W A = QR, A'WA = R'R, and β is found by two triangular solves.
+func polynomial(s *State, degree int, x float64, left, right int) {
+	W := s.w
+	m := right - left + 1
+	q := degree + 1
+
+	var scale float64
+	for j := left; j <= right; j++ {
+		scale = math.Max(scale, math.Abs(s.At(j)-x))
+	}
+	if scale == 0 || degree == 0 {
+		return
+	}
+
+	// column major √W A
+	a := make([]float64, m*q)
+	for j := left; j <= right; j++ {
+		u := (s.At(j) - x) / scale
+		sw := math.Sqrt(W[j])
+		v := sw
+		for k := 0; k < q; k++ {
+			a[k*m+j-left] = v
+			v *= u
+		}
+	}
+
+	r, ok := householderR(a, m, q, q)
+	if !ok {
+		polynomial(s, degree-1, x, left, right)
+		return
+	}
+
+	// solve R'R β = e₁
+	beta := make([]float64, q)
+	beta[0] = 1
+	for i := 0; i < q; i++ {
+		v := beta[i]
+		for k := 0; k < i; k++ {
+			v -= r[k*q+i] * beta[k]
+		}
+		beta[i] = v / r[i*q+i]
+	}
+	for i := q - 1; i >= 0; i-- {
+		v := beta[i]
+		for k := i + 1; k < q; k++ {
+			v -= r[i*q+k] * beta[k]
+		}
+		beta[i] = v / r[i*q+i]
+	}
+
+	for j := left; j <= right; j++ {
+		u := (s.At(j) - x) / scale
+		var v, p float64 = 0, 1
+		for k := 0; k < q; k++ {
+			v += beta[k] * p
+			p *= u
+		}
+		W[j] *= v
+	}
+}
+
+// householderR computes the R factor (row major, q×q) of the QR decomposition of the column major m×q matrix a, which is overwritten.
+// It returns false if any of the first rank columns is linearly dependent on the columns before it.
+func householderR(a []float64, m, q, rank int) (r []float64, ok bool) {
+	if m < rank {
+		return nil, false
+	}
+	r = make([]float64, q*q)
+	var maxDiag float64
+	for k := 0; k < q; k++ {
+		col := a[k*m : (k+1)*m]
+		var norm float64
+		for i := k; i < m; i++ {
+			norm += col[i] * col[i]
+		}
+		norm = math.Sqrt(norm)
+		if k < rank && (norm == 0 || norm <= rankTol*maxDiag) {
+			return nil, false
+		}
+		if k >= m {
+			break
+		}
+		if norm == 0 {
+			continue
+		}
+		maxDiag = math.Max(maxDiag, norm)
+
+		// v = col[k:] + sign(col[k])·‖col[k:]‖e₁
+		alpha := -math.Copysign(norm, col[k])
+		col[k] -= alpha
+		var vnorm float64
+		for i := k; i < m; i++ {
+			vnorm += col[i] * col[i]
+		}
+
+		// apply the reflection to the remaining columns
+		for c := k + 1; c < q; c++ {
+			other := a[c*m : (c+1)*m]
+			var dot float64
+			for i := k; i < m; i++ {
+				dot += col[i] * other[i]
+			}
+			f := 2 * dot / vnorm
+			for i := k; i < m; i++ {
+				other[i] -= f * col[i]
+			}
+			r[k*q+c] = other[k]
+		}
+		r[k*q+k] = alpha
+	}
+	return r, true
+}
@@ -0,0 +1,76 @@
+package loess
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/dawson"
+)
+
+func TestPolynomial(t *testing.T) {
+	n := 30
+	quad := make([]float64, n)
+	cubic := make([]float64, n)
+	for i := range quad {
+		x := float64(i)
+		quad[i] = 0.5*x*x - 3*x + 2
+		cubic[i] = 0.01*x*x*x - 0.2*x*x + x
+	}
+
+	// a polynomial of degree p is reproduced exactly by a local polynomial of degree >= p
+	for _, c := range []struct {
+		data   []float64
+		degree int
+	}{{quad, 2}, {quad, 3}, {cubic, 3}} {
+		smoothed, err := Smooth(c.data, 9, 1, Polynomial(c.degree))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range smoothed {
+			if math.Abs(smoothed[i]-c.data[i]) > 1e-6 {
+				t.Errorf("Degree %d: expected %v at %d. Got %v", c.degree, c.data[i], i, smoothed[i])
+			}
+		}
+	}
+
+	smoothed, err := Smooth(quad, 9, 1, Quadratic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dawson.AllClose(smoothed, quad) {
+		t.Errorf("Expected Quadratic to reproduce a quadratic")
+	}
+
+	// the general solver of degree 1 agrees with the unrolled Linear
+	a := []float64{5, 6.0, 2.0, 4.5, 5, 5, 6.5, 3.5, 4.0, 5, 5, 5.5, 3.5, 5.0, 5}
+	linear, _ := Smooth(a, 5, 1, Linear)
+	s := New(5, a)
+	for i := range a {
+		left, right := s.Bounds(i)
+		if err := s.localWeights(float64(i), float64(left), float64(right)); err != nil {
+			t.Fatal(err)
+		}
+		polynomial(s, 1, float64(i), left, right)
+		var v float64
+		for j := left; j <= right; j++ {
+			v += s.w[j] * a[j]
+		}
+		if !dawson.CloseF64(v, linear[i]) {
+			t.Errorf("At %d: expected %v. Got %v", i, linear[i], v)
+		}
+	}
+
+	// local constant regression is the kernel weighted mean
+	constant, _ := Smooth([]float64{1, 1, 1, 4, 4, 4}, 6, 1, Polynomial(0))
+	for i := range constant {
+		if constant[i] < 1 || constant[i] > 4 {
+			t.Errorf("Expected a weighted mean. Got %v", constant)
+		}
+	}
+
+	// too few points for the degree: the degree is lowered
+	two := []float64{1, 3}
+	if smoothed, err := Smooth(two, 2, 1, Polynomial(4)); err != nil || math.IsNaN(smoothed[0]) {
+		t.Errorf("Expected the degree to be lowered. Got %v (%v)", smoothed, err)
+	}
+}
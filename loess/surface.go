@@ -0,0 +1,331 @@
+package loess
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Surface is a local regression of a response on two or more predictors (although one works too), in the manner of R's loess.
+//
+// The fitted value at a query point is a weighted least squares polynomial fit of its width nearest neighbours.
+// The neighbours are found by a k-d tree, and weighed by the kernel of their distance to the query point, scaled by the distance to the furthest
+// of them. If width is greater than the number of points, the distance is enlarged by (width/n)^(1/d), where d is the number of predictors.
+//
+// Distances are measured after each predictor is divided by its scale. By default, the scale is the 10% trimmed standard deviation of the predictor,
+// so that predictors measured in different units are comparable. See SetScales.
+type Surface struct {
+	width  int
+	degree int
+	kernel Kernel
+
+	x      [][]float64 // predictors, as given
+	y      []float64   // response
+	e      []float64   // external weights
+	scales []float64
+
+	pts  [][]float64 // predictors, divided by the scales
+	tree *kdTree
+
+	eval Evaluation
+	cell float64
+	vt   *vertexTree // built on the first interpolated evaluation
+}
+
+// NewSurface creates a local regression surface of the response y on the predictors x, where x[i] is the vector of predictors of the ith point.
+// e are the optional external weights of the points. The default is a local linear regression with the tricube kernel.
+func NewSurface(width int, x [][]float64, y, e []float64) (*Surface, error) {
+	n := len(x)
+	if n == 0 {
+		return nil, errors.New("Cannot create a surface without points")
+	}
+	if len(y) != n {
+		return nil, errors.Errorf("Expected %d responses. Got %d", n, len(y))
+	}
+	if len(e) != 0 && len(e) != n {
+		return nil, errors.Errorf("Expected %d external weights. Got %d", n, len(e))
+	}
+	if width < 1 {
+		return nil, errors.Errorf("Width must be positive. Got %d", width)
+	}
+	dim := len(x[0])
+	if dim == 0 {
+		return nil, errors.New("Expected at least one predictor")
+	}
+	for i, p := range x {
+		if len(p) != dim {
+			return nil, errors.Errorf("Expected %d predictors for point %d. Got %d", dim, i, len(p))
+		}
+	}
+
+	s := &Surface{
+		width:  width,
+		degree: 1,
+		x:      x,
+		y:      y,
+		e:      e,
+		scales: make([]float64, dim),
+		cell:   defaultCell,
+	}
+	for k := range s.scales {
+		s.scales[k] = trimmedSD(x, k)
+	}
+	s.rescale()
+	return s, nil
+}
+
+// Dims returns the number of predictors.
+func (s *Surface) Dims() int { return len(s.scales) }
+
+// Width returns the number of neighbours used in each local regression.
+func (s *Surface) Width() int { return s.width }
+
+// Degree returns the degree of the local polynomials.
+func (s *Surface) Degree() int { return s.degree }
+
+// SetDegree sets the degree of the local polynomials: 0 (local constant), 1 (local linear, the default) or 2 (local quadratic, with cross terms).
+func (s *Surface) SetDegree(degree int) error {
+	if degree < 0 || degree > 2 {
+		return errors.Errorf("Degree must be 0, 1 or 2. Got %d", degree)
+	}
+	s.degree = degree
+	s.vt = nil
+	return nil
+}
+
+// Kernel returns the kernel used to weigh the neighbours of a point.
+func (s *Surface) Kernel() Kernel { return s.kernel }
+
+// SetKernel sets the kernel used to weigh the neighbours of a point. The default is Tricube.
+func (s *Surface) SetKernel(k Kernel) {
+	s.kernel = k
+	s.vt = nil
+}
+
+// Scales returns the scales of the predictors.
+func (s *Surface) Scales() []float64 { return s.scales }
+
+// SetScales sets the scales of the predictors. Each predictor is divided by its scale before distances are measured.
+// Use 1 for every predictor to measure distances in the original units.
+func (s *Surface) SetScales(scales []float64) error {
+	if len(scales) != len(s.scales) {
+		return errors.Errorf("Expected %d scales. Got %d", len(s.scales), len(scales))
+	}
+	for k, v := range scales {
+		if !(v > 0) || math.IsInf(v, 0) {
+			return errors.Errorf("Scale of predictor %d must be positive and finite. Got %v", k, v)
+		}
+	}
+	copy(s.scales, scales)
+	s.rescale()
+	return nil
+}
+
+// rescale divides the predictors by the scales, and builds the k-d tree.
+func (s *Surface) rescale() {
+	s.pts = make([][]float64, len(s.x))
+	for i, p := range s.x {
+		s.pts[i] = s.scale(p, nil)
+	}
+	s.tree = newKDTree(s.pts, kdLeafSize, 0)
+	s.vt = nil
+}
+
+// scale divides q by the scales, writing the result to buf if it is large enough.
+func (s *Surface) scale(q, buf []float64) []float64 {
+	if cap(buf) < len(q) {
+		buf = make([]float64, len(q))
+	}
+	buf = buf[:len(q)]
+	for k, v := range q {
+		buf[k] = v / s.scales[k]
+	}
+	return buf
+}
+
+// Predict returns the fitted value of the surface at q.
+func (s *Surface) Predict(q []float64) (float64, error) {
+	if len(q) != s.Dims() {
+		return 0, errors.Errorf("Expected a query point with %d predictors. Got %d", s.Dims(), len(q))
+	}
+	return s.evaluate(s.scale(q, nil), nil)
+}
+
+// Evaluate returns the fitted values of the surface at each of the query points.
+func (s *Surface) Evaluate(qs [][]float64) ([]float64, error) {
+	retVal := make([]float64, len(qs))
+	var q []float64
+	var buf neighbours
+	for i, p := range qs {
+		if len(p) != s.Dims() {
+			return nil, errors.Errorf("Expected a query point with %d predictors. Got %d", s.Dims(), len(p))
+		}
+		q = s.scale(p, q)
+		v, err := s.evaluate(q, &buf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to evaluate the surface at %v", p)
+		}
+		retVal[i] = v
+	}
+	return retVal, nil
+}
+
+// Fitted returns the fitted values of the surface at each of the points.
+func (s *Surface) Fitted() ([]float64, error) { return s.Evaluate(s.x) }
+
+// regress performs the local regression at the scaled query point q, returning the fitted value followed by its gradient.
+// buf, if not nil, is reused for the neighbour search.
+func (s *Surface) regress(q []float64, buf *neighbours) ([]float64, error) {
+	n := len(s.pts)
+	k := s.width
+	if k > n {
+		k = n
+	}
+	var nb neighbours
+	if buf != nil {
+		nb = s.tree.nearest(q, k, *buf)
+		*buf = nb
+	} else {
+		nb = s.tree.nearest(q, k, nil)
+	}
+
+	var lambda float64
+	for _, v := range nb {
+		lambda = math.Max(lambda, v.dist2)
+	}
+	lambda = math.Sqrt(lambda)
+	if s.width > n {
+		lambda *= math.Pow(float64(s.width)/float64(n), 1/float64(s.Dims()))
+	}
+
+	// the kernel weights, following localWeights
+	w := make([]float64, len(nb))
+	var sum float64
+	for j, v := range nb {
+		switch {
+		case lambda <= 0:
+			w[j] = 1
+		case s.kernel == Tricube:
+			delta := math.Sqrt(v.dist2)
+			if delta <= 0.00001*lambda {
+				w[j] = 1
+			} else if delta <= 0.99999*lambda {
+				w[j] = tricube(delta / lambda)
+			}
+		default:
+			w[j] = s.kernel.Weight(math.Sqrt(v.dist2) / lambda)
+		}
+		if len(s.e) > 0 {
+			w[j] *= s.e[v.i]
+		}
+		sum += w[j]
+	}
+	if sum <= 0 {
+		return nil, errTotal
+	}
+	for j := range w {
+		w[j] /= sum
+	}
+	return s.polynomial(q, nb, w, lambda, s.degree), nil
+}
+
+// polynomial fits a polynomial of the given degree to the neighbours by weighted least squares.
+// It returns its value at q, followed by its gradient with respect to the scaled predictors.
+// The predictors are centered on q and divided by lambda, so the columns of the design matrix are well scaled.
+// As with localPolynomial, the coefficients are found by a QR decomposition of [√W A | √W y], and the degree is lowered if A is rank deficient.
+func (s *Surface) polynomial(q []float64, nb neighbours, w []float64, lambda float64, degree int) []float64 {
+	dim := len(q)
+	if degree == 0 || lambda <= 0 {
+		retVal := make([]float64, 1+dim)
+		for j, p := range nb {
+			retVal[0] += w[j] * s.y[p.i]
+		}
+		return retVal
+	}
+
+	cols := 1 + dim
+	if degree == 2 {
+		cols += dim * (dim + 1) / 2
+	}
+	m := len(nb)
+	a := make([]float64, m*(cols+1))
+	u := make([]float64, dim)
+	for j, p := range nb {
+		for k := range u {
+			u[k] = (s.pts[p.i][k] - q[k]) / lambda
+		}
+		sw := math.Sqrt(w[j])
+		a[j] = sw
+		c := 1
+		for k := range u {
+			a[c*m+j] = sw * u[k]
+			c++
+		}
+		if degree == 2 {
+			for k := range u {
+				for l := k; l < dim; l++ {
+					a[c*m+j] = sw * u[k] * u[l]
+					c++
+				}
+			}
+		}
+		a[cols*m+j] = sw * s.y[p.i]
+	}
+
+	r, ok := householderR(a, m, cols+1, cols)
+	if !ok {
+		return s.polynomial(q, nb, w, lambda, degree-1)
+	}
+	// back substitute R c = Q'√W y
+	p := cols + 1
+	coef := make([]float64, cols)
+	for i := cols - 1; i >= 0; i-- {
+		v := r[i*p+cols]
+		for k := i + 1; k < cols; k++ {
+			v -= r[i*p+k] * coef[k]
+		}
+		coef[i] = v / r[i*p+i]
+	}
+	retVal := coef[:1+dim]
+	for k := 1; k <= dim; k++ {
+		retVal[k] /= lambda
+	}
+	return retVal
+}
+
+// trimmedSD is the standard deviation of the kth predictor, after the smallest and largest 10% of its values are trimmed.
+// If that is 0 (e.g. the predictor is mostly constant), the untrimmed standard deviation is used, and failing that, 1.
+func trimmedSD(x [][]float64, k int) float64 {
+	v := make([]float64, len(x))
+	for i, p := range x {
+		v[i] = p[k]
+	}
+	sort.Float64s(v)
+	trim := int(math.Ceil(0.1 * float64(len(v))))
+	if trim < len(v)-trim {
+		if retVal := sd(v[trim : len(v)-trim]); retVal > 0 {
+			return retVal
+		}
+	}
+	if retVal := sd(v); retVal > 0 {
+		return retVal
+	}
+	return 1
+}
+
+func sd(v []float64) float64 {
+	if len(v) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	var ss float64
+	for _, x := range v {
+		ss += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(ss / float64(len(v)-1))
+}
@@ -0,0 +1,196 @@
+package loess
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func grid(n int, f func(a, b float64) float64) (x [][]float64, y []float64) {
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			a, b := float64(i)/float64(n-1), float64(j)/float64(n-1)
+			x = append(x, []float64{a, b})
+			y = append(y, f(a, b))
+		}
+	}
+	return x, y
+}
+
+func TestKDTreeNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	pts := make([][]float64, 500)
+	for i := range pts {
+		pts[i] = []float64{r.Float64(), r.Float64(), r.Float64()}
+	}
+	tree := newKDTree(pts, kdLeafSize, 0)
+	for trial := 0; trial < 20; trial++ {
+		q := []float64{r.Float64(), r.Float64(), r.Float64()}
+		k := 1 + r.Intn(50)
+		got := tree.nearest(q, k, nil)
+
+		expected := make([]float64, len(pts))
+		for i, p := range pts {
+			expected[i] = dist2(p, q)
+		}
+		sort.Float64s(expected)
+		dists := make([]float64, len(got))
+		for i, n := range got {
+			dists[i] = n.dist2
+		}
+		sort.Float64s(dists)
+		if len(dists) != k {
+			t.Fatalf("Expected %d neighbours. Got %d", k, len(dists))
+		}
+		for i := range dists {
+			if dists[i] != expected[i] {
+				t.Fatalf("Trial %d: neighbour %d is at %v. Expected %v", trial, i, dists[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestSurface(t *testing.T) {
+	// a local linear surface reproduces a plane exactly
+	x, y := grid(15, func(a, b float64) float64 { return 1 + 2*a - 3*b })
+	s, err := NewSurface(30, x, y, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range [][]float64{{0.5, 0.5}, {0, 0}, {0.93, 0.12}, {1.1, -0.1}} {
+		v, err := s.Predict(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := 1 + 2*q[0] - 3*q[1]; math.Abs(v-expected) > 1e-9 {
+			t.Errorf("Expected %v at %v. Got %v", expected, q, v)
+		}
+	}
+
+	// a local quadratic surface reproduces a quadratic exactly, cross terms included
+	quad := func(a, b float64) float64 { return a*a - a*b + 2*b*b + a }
+	x, y = grid(15, quad)
+	if s, err = NewSurface(40, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetDegree(2); err != nil {
+		t.Fatal(err)
+	}
+	fitted, err := s.Fitted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range x {
+		if math.Abs(fitted[i]-quad(p[0], p[1])) > 1e-9 {
+			t.Fatalf("Expected %v at %v. Got %v", quad(p[0], p[1]), p, fitted[i])
+		}
+	}
+
+	if err = s.SetDegree(3); err == nil {
+		t.Errorf("Expected an error for degree 3")
+	}
+	if _, err = s.Predict([]float64{1}); err == nil {
+		t.Errorf("Expected an error for a query point of the wrong dimension")
+	}
+	if _, err = NewSurface(10, x, y[1:], nil); err == nil {
+		t.Errorf("Expected an error for mismatched lengths")
+	}
+}
+
+func TestSurfaceScaling(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	var x, scaled [][]float64
+	var y []float64
+	for i := 0; i < 400; i++ {
+		a, b := r.Float64(), r.Float64()
+		x = append(x, []float64{a, b})
+		scaled = append(scaled, []float64{a, 1000 * b})
+		y = append(y, math.Sin(3*a)*math.Cos(3*b)+0.1*r.NormFloat64())
+	}
+	s, err := NewSurface(40, x, y, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSurface(40, scaled, y, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// normalization makes the fit independent of the units of the predictors
+	for _, q := range [][]float64{{0.2, 0.3}, {0.5, 0.9}} {
+		v, _ := s.Predict(q)
+		vs, _ := ss.Predict([]float64{q[0], 1000 * q[1]})
+		if math.Abs(v-vs) > 1e-9 {
+			t.Errorf("Expected normalized surfaces to agree at %v. Got %v and %v", q, v, vs)
+		}
+	}
+
+	// without normalization, the second predictor dominates the distances
+	if err = ss.SetScales([]float64{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := s.Predict([]float64{0.2, 0.3})
+	vs, _ := ss.Predict([]float64{0.2, 300})
+	if math.Abs(v-vs) < 1e-6 {
+		t.Errorf("Expected the scales to change the fit")
+	}
+	if err = ss.SetScales([]float64{1, 0}); err == nil {
+		t.Errorf("Expected an error for a zero scale")
+	}
+}
+
+func TestSurfaceInterpolate(t *testing.T) {
+	// a plane is reproduced exactly by the interpolation, as the vertices have its exact values and gradients
+	x, y := grid(30, func(a, b float64) float64 { return 1 + 2*a - 3*b })
+	s, err := NewSurface(60, x, y, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEvaluation(Interpolate)
+	for _, q := range [][]float64{{0.5, 0.5}, {0, 0}, {0.93, 0.12}, {0.31, 0.77}} {
+		v, err := s.Predict(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := 1 + 2*q[0] - 3*q[1]; math.Abs(v-expected) > 1e-9 {
+			t.Errorf("Expected %v at %v. Got %v", expected, q, v)
+		}
+	}
+	if s.vt == nil || len(s.vt.vals) < 4 {
+		t.Fatalf("Expected a vertex tree to be built")
+	}
+
+	// a smooth surface is close to the direct surface
+	r := rand.New(rand.NewSource(1337))
+	f := func(a, b float64) float64 { return math.Sin(3*a) * math.Cos(2*b) }
+	x, y = nil, nil
+	for i := 0; i < 2000; i++ {
+		a, b := r.Float64(), r.Float64()
+		x = append(x, []float64{a, b})
+		y = append(y, f(a, b)+0.05*r.NormFloat64())
+	}
+	if s, err = NewSurface(200, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	direct, err := s.Fitted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEvaluation(Interpolate)
+	interpolated, err := s.Fitted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxAbsDiff(direct, interpolated); d > 0.01 {
+		t.Errorf("Expected the interpolated surface to be within 0.01 of the direct surface. Max difference: %v", d)
+	}
+
+	// points outside of the cells are evaluated directly
+	s.SetEvaluation(Direct)
+	expected, _ := s.Predict([]float64{2, 2})
+	s.SetEvaluation(Interpolate)
+	if got, _ := s.Predict([]float64{2, 2}); got != expected {
+		t.Errorf("Expected a point outside of the cells to be evaluated directly. Got %v, expected %v", got, expected)
+	}
+}
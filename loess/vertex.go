@@ -0,0 +1,172 @@
+package loess
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultCell is the default fraction of the width that bounds the number of points in a cell of a vertex tree. It is R's default.
+	defaultCell = 0.2
+
+	// vertexMargin is the fraction of the extent of the points by which the root cell of a vertex tree is enlarged on every side.
+	vertexMargin = 0.05
+)
+
+// Evaluation is how a Surface is evaluated at a query point.
+type Evaluation int
+
+const (
+	// Direct performs a local regression at every query point. This is R's surface = "direct".
+	Direct Evaluation = iota
+
+	// Interpolate performs local regressions only at the vertices of the cells of a k-d tree, and blends them elsewhere,
+	// as netlib's loess does (R's surface = "interpolate").
+	//
+	// The cells are split at the median of their points until they hold at most floor(cell × width) points (see SetCell).
+	// The value and gradient of the local fit are computed at each vertex, and a query point is interpolated from the vertices of its cell
+	// by cubic Hermite interpolation along each dimension in turn, with the remaining components of the gradients interpolated linearly.
+	//
+	// Each evaluation then costs O(log n + 2^d) instead of a neighbour search and a regression. The interpolated surface differs from
+	// the direct one by the error of the cubic interpolation of the direct surface, which is small for a smooth surface and cells that are
+	// small relative to the width. Query points outside of the cells (the bounding box of the points, enlarged by 5% on every side)
+	// are evaluated directly.
+	Interpolate
+)
+
+func (m Evaluation) String() string {
+	switch m {
+	case Direct:
+		return "Direct"
+	case Interpolate:
+		return "Interpolate"
+	}
+	return fmt.Sprintf("Evaluation(%d)", int(m))
+}
+
+// Evaluation returns how the surface is evaluated.
+func (s *Surface) Evaluation() Evaluation { return s.eval }
+
+// SetEvaluation sets how the surface is evaluated. The default is Direct.
+//
+// With Interpolate, the vertex tree is built on the first evaluation, and rebuilt after the surface is changed.
+// So a surface is only safe for concurrent evaluation after it has been evaluated once.
+func (s *Surface) SetEvaluation(m Evaluation) { s.eval = m }
+
+// Cell returns the fraction of the width that bounds the number of points in a cell of the vertex tree.
+func (s *Surface) Cell() float64 { return s.cell }
+
+// SetCell sets the fraction of the width that bounds the number of points in a cell of the vertex tree. The default is 0.2.
+// Smaller cells are interpolated more accurately, but have more vertices to fit.
+func (s *Surface) SetCell(cell float64) error {
+	if !(cell > 0) {
+		return errors.Errorf("Cell must be positive. Got %v", cell)
+	}
+	s.cell = cell
+	s.vt = nil
+	return nil
+}
+
+// vertexTree is a k-d tree whose leaves have the local fits at their corners.
+type vertexTree struct {
+	tree *kdTree
+	vals [][]float64 // the fitted value and gradient at each vertex
+}
+
+// evaluate returns the fitted value of the surface at the scaled query point q.
+func (s *Surface) evaluate(q []float64, buf *neighbours) (float64, error) {
+	if s.eval == Interpolate {
+		if s.vt == nil {
+			if err := s.buildVertices(); err != nil {
+				return 0, err
+			}
+		}
+		if v, ok := s.vt.interpolate(q); ok {
+			return v, nil
+		}
+	}
+	fit, err := s.regress(q, buf)
+	if err != nil {
+		return 0, err
+	}
+	return fit[0], nil
+}
+
+// buildVertices builds the vertex tree, and fits the surface at every vertex.
+func (s *Surface) buildVertices() error {
+	dim := s.Dims()
+	fc := int(math.Floor(s.cell * float64(s.width)))
+	vt := &vertexTree{tree: newKDTree(s.pts, fc, vertexMargin)}
+
+	// vertices are shared between cells, so they are deduplicated by their coordinates
+	index := make(map[string]int)
+	key := make([]byte, 8*dim)
+	corner := make([]float64, dim)
+	var buf neighbours
+	var err error
+	vt.tree.leaves(func(n *kdNode) {
+		n.vertices = make([]int, 1<<uint(dim))
+		for c := range n.vertices {
+			for k := range corner {
+				corner[k] = n.lo[k]
+				if c&(1<<uint(k)) != 0 {
+					corner[k] = n.hi[k]
+				}
+				binary.LittleEndian.PutUint64(key[8*k:], math.Float64bits(corner[k]))
+			}
+			v, ok := index[string(key)]
+			if !ok {
+				fit, fitErr := s.regress(corner, &buf)
+				if fitErr != nil {
+					if err == nil {
+						err = errors.Wrapf(fitErr, "Unable to fit the vertex at %v", corner)
+					}
+					fit = make([]float64, 1+dim)
+				}
+				v = len(vt.vals)
+				vt.vals = append(vt.vals, fit)
+				index[string(key)] = v
+			}
+			n.vertices[c] = v
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.vt = vt
+	return nil
+}
+
+// interpolate blends the fits at the vertices of the cell containing q. It returns false if q is not in any cell.
+func (vt *vertexTree) interpolate(q []float64) (float64, bool) {
+	n := vt.tree.find(q)
+	if n == nil {
+		return 0, false
+	}
+	cur := make([][]float64, len(n.vertices))
+	for c, v := range n.vertices {
+		cur[c] = append([]float64(nil), vt.vals[v]...)
+	}
+
+	// interpolate along the last dimension, halving the number of corners, until one is left
+	for k := len(q) - 1; k >= 0; k-- {
+		half := 1 << uint(k)
+		h := n.hi[k] - n.lo[k]
+		var t float64
+		if h > 0 {
+			t = (q[k] - n.lo[k]) / h
+		}
+		for c := 0; c < half; c++ {
+			a, b := cur[c], cur[c|half]
+			a[0] = hermite(a[0], b[0], a[1+k]*h, b[1+k]*h, t)
+			for j := 1; j <= k; j++ {
+				a[j] += t * (b[j] - a[j])
+			}
+		}
+		cur = cur[:half]
+	}
+	return cur[0][0], true
+}
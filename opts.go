@@ -14,6 +14,16 @@ type Config struct {
 
 	// Which weight updating function should be used?
 	Fn loess.WeightUpdate
+
+	// Kernel is the kernel used to weigh the neighbours in the LOESS smoother. The zero value is the tricube kernel.
+	Kernel loess.Kernel
+
+	// Fast smooths with the FFT based fast path of local linear regression, for long series with wide windows. See loess.WithFast.
+	// As the trend is smoothed in place, a fast trend is not the same as the exact one, only close to it.
+	Fast bool
+
+	// Interpolation is how the points skipped between jumps are filled in. The zero value is linear interpolation.
+	Interpolation loess.Interpolation
 }
 
 // Opt is a function that helps build the conf
@@ -56,6 +66,47 @@ func WithIter(n int) Opt {
 	}
 }
 
+// WithFourier estimates the seasonal component with k Fourier harmonics of the period, instead of smoothing the cycle-subseries.
+// The trend is still estimated by LOESS in the inner loop.
+//
+// This is useful for long periods (e.g. 365 for daily data), where each cycle-subseries has too few points to be smoothed well.
+// If k <= 0, the number of harmonics is selected automatically (up to 20) by the criterion set with WithFourierCriterion, AIC by default.
+func WithFourier(k int) Opt {
+	return func(s *state) {
+		if s.fourier == nil {
+			s.fourier = new(fourierState)
+		}
+		s.fourier.k = k
+	}
+}
+
+// WithFourierCriterion sets the criterion by which the number of harmonics is automatically selected. See WithFourier.
+func WithFourierCriterion(c FourierCriterion) Opt {
+	return func(s *state) {
+		if s.fourier == nil {
+			s.fourier = new(fourierState)
+		}
+		s.fourier.crit = c
+	}
+}
+
+// WithTrendSlope estimates the local slope of the trend (e.g. the growth per day of daily data), which is returned in Result.TrendSlope.
+// It is the slope of a local linear regression, estimated in the last smoothing of the trend on the same windows,
+// with the same kernel and robustness weights (see loess.WithSlopes).
+func WithTrendSlope() Opt {
+	return func(s *state) {
+		s.trendSlope = true
+	}
+}
+
+// WithParallel smooths the cycle-subseries across up to n goroutines. The subseries are independent, so the results are
+// identical to those of smoothing them sequentially, which is the default (n <= 1). This pays off for large periods, such as 168 for hourly data.
+func WithParallel(n int) Opt {
+	return func(s *state) {
+		s.parallel = n
+	}
+}
+
 // DefaultSeasonal returns the default configuration for the operation that works on the seasonal component.
 func DefaultSeasonal(width int) Config {
 	if width <= 0 {
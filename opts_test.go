@@ -1,8 +1,11 @@
 package stl
 
 import (
+	"math"
 	"testing"
 	"testing/quick"
+
+	"github.com/chewxy/stl/loess"
 )
 
 func TestDefaults(t *testing.T) {
@@ -85,3 +88,210 @@ func TestDefaults(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestKernelConfig(t *testing.T) {
+	data := loadCO2(t)
+	X := make([]float64, len(data))
+	copy(X, data)
+	tricubed := Decompose(X, 12, 35, Additive())
+	if tricubed.Err != nil {
+		t.Fatal(tricubed.Err)
+	}
+
+	for _, k := range []loess.Kernel{loess.Epanechnikov, loess.Gaussian} {
+		X := make([]float64, len(data))
+		copy(X, data)
+		seasonal := DefaultSeasonal(35)
+		seasonal.Kernel = k
+		trend := DefaultTrend(12, 35)
+		trend.Kernel = k
+		res := Decompose(X, 12, 35, Additive(), WithSeasonalConfig(seasonal), WithTrendConfig(trend))
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		var diff float64
+		for i := range res.Trend {
+			diff += math.Abs(res.Trend[i] - tricubed.Trend[i])
+		}
+		if diff == 0 {
+			t.Errorf("%v: expected the kernel to change the trend", k)
+		}
+	}
+}
+
+func TestFastConfig(t *testing.T) {
+	data := loadCO2(t)
+	X := make([]float64, len(data))
+	copy(X, data)
+	exact := Decompose(X, 12, 35, Additive())
+	if exact.Err != nil {
+		t.Fatal(exact.Err)
+	}
+
+	X = make([]float64, len(data))
+	copy(X, data)
+	seasonal, trend, lowpass := DefaultSeasonal(35), DefaultTrend(12, 35), DefaultLowPass(12)
+	seasonal.Fast, lowpass.Fast = true, true
+	fast := Decompose(X, 12, 35, Additive(), WithSeasonalConfig(seasonal), WithTrendConfig(trend), WithLowpassConfig(lowpass))
+	if fast.Err != nil {
+		t.Fatal(fast.Err)
+	}
+	for i := range fast.Trend {
+		if math.Abs(fast.Trend[i]-exact.Trend[i]) > 1e-8 || math.Abs(fast.Seasonal[i]-exact.Seasonal[i]) > 1e-8 {
+			t.Fatalf("Fast path differs from the exact path at %d: trend %v vs %v, seasonal %v vs %v",
+				i, fast.Trend[i], exact.Trend[i], fast.Seasonal[i], exact.Seasonal[i])
+		}
+	}
+
+	// the trend is smoothed in place, which the fast path does out of place. So the trend is only expected to be close.
+	X = make([]float64, len(data))
+	copy(X, data)
+	trend.Fast = true
+	fast = Decompose(X, 12, 35, Additive(), WithSeasonalConfig(seasonal), WithTrendConfig(trend), WithLowpassConfig(lowpass))
+	if fast.Err != nil {
+		t.Fatal(fast.Err)
+	}
+	var diff float64
+	for i := range fast.Trend {
+		diff = math.Max(diff, math.Abs(fast.Trend[i]-exact.Trend[i]))
+	}
+	if diff > 1 {
+		t.Errorf("Expected the fast trend to be close to the exact trend. Max difference: %v", diff)
+	}
+}
+
+func TestInterpolationConfig(t *testing.T) {
+	data := loadCO2(t)
+	X := make([]float64, len(data))
+	copy(X, data)
+	linear := Decompose(X, 12, 35, Additive())
+	if linear.Err != nil {
+		t.Fatal(linear.Err)
+	}
+
+	X = make([]float64, len(data))
+	copy(X, data)
+	trend := DefaultTrend(12, 35)
+	trend.Interpolation = loess.HermiteInterpolation
+	hermite := Decompose(X, 12, 35, Additive(), WithTrendConfig(trend))
+	if hermite.Err != nil {
+		t.Fatal(hermite.Err)
+	}
+
+	// the trend is smoothed with jumps, so the interpolation changes it
+	if trend.Jump <= 1 {
+		t.Fatalf("Expected the default trend to jump. Got %d", trend.Jump)
+	}
+	var diff float64
+	for i := range hermite.Trend {
+		diff = math.Max(diff, math.Abs(hermite.Trend[i]-linear.Trend[i]))
+	}
+	if diff == 0 || diff > 1 {
+		t.Errorf("Expected the Hermite trend to differ slightly from the linearly interpolated trend. Max difference: %v", diff)
+	}
+}
+
+func TestWithParallel(t *testing.T) {
+	data := loadCO2(t)
+	decompose := func(opts ...Opt) (Result, Result) {
+		X := append([]float64(nil), data...)
+		res := Decompose(X, 12, 35, Additive(), opts...)
+		X = append([]float64(nil), data...)
+		frac := DecomposeFractional(X, 12.5, 35, Additive(), opts...)
+		return res, frac
+	}
+	seqRes, seqFrac := decompose(WithRobustIter(2))
+	parRes, parFrac := decompose(WithRobustIter(2), WithParallel(4))
+	for _, c := range []struct {
+		name     string
+		seq, par Result
+	}{{"Decompose", seqRes, parRes}, {"DecomposeFractional", seqFrac, parFrac}} {
+		if c.seq.Err != nil || c.par.Err != nil {
+			t.Fatalf("%s: %v, %v", c.name, c.seq.Err, c.par.Err)
+		}
+		for i := range c.seq.Seasonal {
+			if c.seq.Seasonal[i] != c.par.Seasonal[i] || c.seq.Trend[i] != c.par.Trend[i] {
+				t.Fatalf("%s: parallel result differs from the sequential result at %d", c.name, i)
+			}
+		}
+	}
+}
+
+func TestRobustWeights(t *testing.T) {
+	data := loadCO2(t)
+	data[200] += 100 // an outlier
+
+	res := Decompose(append([]float64(nil), data...), 12, 35, Additive())
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	for i, w := range res.Weights {
+		if w != 1 {
+			t.Fatalf("Expected the weights of a non-robust decomposition to be 1. Weight %d is %v", i, w)
+		}
+	}
+
+	res = Decompose(append([]float64(nil), data...), 12, 35, Additive(), WithRobustIter(2))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if len(res.Weights) != len(data) {
+		t.Fatalf("Expected %d weights. Got %d", len(data), len(res.Weights))
+	}
+	if res.Weights[200] != 0 {
+		t.Errorf("Expected the outlier to have a weight of 0. Got %v", res.Weights[200])
+	}
+	var sum float64
+	for _, w := range res.Weights {
+		if w < 0 || w > 1 {
+			t.Fatalf("Expected weights in [0, 1]. Got %v", w)
+		}
+		sum += w
+	}
+	if mean := sum / float64(len(data)); mean < 0.8 {
+		t.Errorf("Expected most points to have a high weight. The mean weight is %v", mean)
+	}
+}
+
+func TestTrendSlope(t *testing.T) {
+	// a linear trend of 0.5 per observation, with a seasonality of period 4
+	data := make([]float64, 48)
+	for i := range data {
+		data[i] = 10 + 0.5*float64(i) + []float64{1, -1, 2, -2}[i%4]
+	}
+	res := Decompose(append([]float64(nil), data...), 4, 7, Additive(), WithTrendSlope())
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	frac := DecomposeFractional(append([]float64(nil), data...), 4, 7, Additive(), WithTrendSlope())
+	if frac.Err != nil {
+		t.Fatal(frac.Err)
+	}
+	if len(res.TrendSlope) != len(data) || len(frac.TrendSlope) != len(data) {
+		t.Fatalf("Expected %d slopes. Got %d and %d", len(data), len(res.TrendSlope), len(frac.TrendSlope))
+	}
+	for i := range data {
+		if math.Abs(res.TrendSlope[i]-0.5) > 1e-6 {
+			t.Errorf("TrendSlope[%d]: expected 0.5. Got %v", i, res.TrendSlope[i])
+		}
+		if d := res.TrendSlope[i] - frac.TrendSlope[i]; math.Abs(d) > 1e-9 {
+			t.Errorf("TrendSlope[%d]: Decompose gave %v, DecomposeFractional gave %v", i, res.TrendSlope[i], frac.TrendSlope[i])
+		}
+	}
+	// a curved trend: smoothing the trend again to estimate its slope would flatten the curvature, and bias the slope
+	// by about 0.02 in the interior
+	data = make([]float64, 96)
+	for i := range data {
+		x := float64(i)
+		data[i] = 0.05*x*x + []float64{1, -1, 2, -2}[i%4]
+	}
+	res = Decompose(data, 4, 7, Additive(), WithTrendSlope(), WithTrendConfig(Config{Width: 35, Jump: 1, Fn: loess.Linear}))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	for i := 20; i < 76; i++ {
+		if expected := 0.1 * float64(i); math.Abs(res.TrendSlope[i]-expected) > 0.01 {
+			t.Errorf("TrendSlope[%d]: expected %v. Got %v", i, expected, res.TrendSlope[i])
+		}
+	}
+}
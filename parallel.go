@@ -0,0 +1,53 @@
+package stl
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// forEach calls fn for every i in [0, n), across up to parallel goroutines. If parallel <= 1, the calls are made sequentially, in order.
+//
+// The error returned is that of the smallest i for which fn failed, as it would be sequentially. A panic in fn is raised again in the calling goroutine.
+func forEach(n, parallel int, fn func(i int) error) error {
+	if parallel <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if parallel > n {
+		parallel = n
+	}
+
+	errs := make([]error, n)
+	var next int64 = -1
+	var wg sync.WaitGroup
+	var once sync.Once
+	var panicked interface{}
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() { panicked = r })
+				}
+			}()
+			for i := int(atomic.AddInt64(&next, 1)); i < n; i = int(atomic.AddInt64(&next, 1)) {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
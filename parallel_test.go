@@ -0,0 +1,41 @@
+package stl
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	for _, parallel := range []int{0, 1, 3, 100} {
+		visited := make([]int, 50)
+		err := forEach(len(visited), parallel, func(i int) error {
+			visited[i]++
+			if i == 7 || i == 30 {
+				return fmt.Errorf("failed at %d", i)
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "failed at 7" {
+			t.Errorf("parallel %d: expected the error of the smallest index. Got %v", parallel, err)
+		}
+		if parallel > 1 {
+			for i, v := range visited {
+				if v != 1 {
+					t.Errorf("parallel %d: %d was visited %d times", parallel, i, v)
+				}
+			}
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("Expected the panic to be raised again. Got %v", r)
+		}
+	}()
+	forEach(10, 4, func(i int) error {
+		if i == 5 {
+			panic("boom")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package stl
+
+import (
+	"math"
+
+	"github.com/chewxy/stl/loess"
+)
+
+// phaseState is the equivalent of subcycleState for non-integer periods.
+//
+// Observation i is at cycle position t = i/period. Its phase is the fractional part of t, and it belongs to one of
+// round(period) phase bins. Each bin is a subseries of observations of a similar phase, irregularly spaced in t,
+// which are smoothed with loess. The smoothed subseries are then extended one (rounded up) period backwards and forwards.
+type phaseState struct {
+	period float64
+	bins   int
+	ext    int // extension on either side. The smoothed result has len(data) + 2*ext elements
+
+	members [][]int     // indices of the observations (in the extended series) in each bin
+	pos     [][]float64 // cycle positions of the members of each bin
+	data    [][]float64
+	weights [][]float64
+
+	parallel int // number of goroutines to smooth the bins with
+
+	Config // for any loess smoothing
+}
+
+func newPhaseState(conf Config, size int, period float64) *phaseState {
+	bins := int(math.Floor(period + 0.5))
+	ext := int(math.Ceil(period))
+	retVal := &phaseState{
+		period:  period,
+		bins:    bins,
+		ext:     ext,
+		members: make([][]int, bins),
+		pos:     make([][]float64, bins),
+		data:    make([][]float64, bins),
+		weights: make([][]float64, bins),
+
+		Config: conf,
+	}
+
+	// observations that are in the data, followed by the extended positions
+	for i := 0; i < size; i++ {
+		b := retVal.bin(i)
+		retVal.members[b] = append(retVal.members[b], i)
+		retVal.pos[b] = append(retVal.pos[b], float64(i)/period)
+	}
+	for b := range retVal.data {
+		retVal.data[b] = make([]float64, len(retVal.members[b]))
+		retVal.weights[b] = make([]float64, len(retVal.members[b]))
+	}
+	for i := -ext; i < size+ext; i++ {
+		if i == 0 {
+			i = size
+		}
+		b := retVal.bin(i)
+		retVal.members[b] = append(retVal.members[b], i)
+	}
+	return retVal
+}
+
+// bin returns the phase bin of the ith observation.
+func (s *phaseState) bin(i int) int {
+	t := float64(i) / s.period
+	phase := t - math.Floor(t)
+	b := int(phase * float64(s.bins))
+	if b >= s.bins {
+		b = s.bins - 1
+	}
+	return b
+}
+
+// smoothSeasonal smooths the phase bins of X, writing the extended result into retVal, which must have len(X) + 2*ext elements.
+func (s *phaseState) smoothSeasonal(X []float64, weights []float64, retVal []float64) error {
+	return forEach(s.bins, s.parallel, func(b int) error {
+		data, w, pos := s.data[b], s.weights[b], s.pos[b]
+		if len(data) == 0 {
+			return nil
+		}
+		for j, i := range s.members[b][:len(data)] {
+			data[j] = X[i]
+			if len(weights) > 0 {
+				w[j] = weights[i]
+			} else {
+				w[j] = 1
+			}
+		}
+
+		l, err := loess.NewWithPositions(s.Width, pos, data, w)
+		if err != nil {
+			return err
+		}
+		smoothed := make([]float64, len(data))
+		l.SetKernel(s.Kernel)
+		l.SetInterpolation(s.Interpolation)
+		if _, err = loess.UnsafeSmooth(l, s.Width, s.Jump, s.Fn, smoothed); err != nil {
+			return err
+		}
+		for j, i := range s.members[b][:len(data)] {
+			retVal[i+s.ext] = smoothed[j]
+		}
+
+		// extend the subseries
+		for _, i := range s.members[b][len(data):] {
+			point, err := loess.Predict(l, s.Fn, float64(i)/s.period)
+			if err != nil {
+				// fall back to the nearest smoothed value
+				if i < 0 {
+					point = smoothed[0]
+				} else {
+					point = smoothed[len(smoothed)-1]
+				}
+			}
+			retVal[i+s.ext] = point
+		}
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package stl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDecomposeFractional(t *testing.T) {
+	// 10 years of weekly data with a yearly seasonality
+	const period = 365.25 / 7
+	r := rand.New(rand.NewSource(1337))
+	n := 522
+	data := make([]float64, n)
+	seasonal := make([]float64, n)
+	trend := make([]float64, n)
+	for i := range data {
+		seasonal[i] = 5 * math.Sin(2*math.Pi*float64(i)/period)
+		trend[i] = 100 + 0.05*float64(i)
+		data[i] = trend[i] + seasonal[i] + 0.1*r.NormFloat64()
+	}
+
+	res := DecomposeFractional(data, period, 7, Additive(), WithIter(2))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	var sse float64
+	for i := range seasonal {
+		d := res.Seasonal[i] - seasonal[i]
+		sse += d * d
+	}
+	if rmse := math.Sqrt(sse / float64(n)); rmse > 0.5 {
+		t.Errorf("Seasonal component RMSE too large: %v", rmse)
+	}
+	for i := range data {
+		if d := res.Data[i] - res.Trend[i] - res.Seasonal[i] - res.Resid[i]; math.Abs(d) > 1e-9 {
+			t.Fatalf("Components do not sum to data at %d", i)
+		}
+	}
+
+	if res := DecomposeFractional(data[:100], period, 7, Additive()); res.Err == nil {
+		t.Errorf("Expected an error for a series shorter than two periods")
+	}
+}
+
+func TestDecomposeIntegerPeriod(t *testing.T) {
+	// the seasonal component of each observation must come from its own phase, not from the second phase of the first period
+	pattern := []float64{1, -1, 2, -2}
+	data := make([]float64, 48)
+	for i := range data {
+		data[i] = 10 + 0.5*float64(i) + pattern[i%4]
+	}
+
+	res := Decompose(append([]float64(nil), data...), 4, 7, Additive())
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	for i := 8; i < len(data)-8; i++ {
+		if d := res.Seasonal[i] - pattern[i%4]; math.Abs(d) > 0.1 {
+			t.Errorf("Seasonal[%d]: expected about %v. Got %v", i, pattern[i%4], res.Seasonal[i])
+		}
+	}
+
+	frac := DecomposeFractional(append([]float64(nil), data...), 4, 7, Additive())
+	if frac.Err != nil {
+		t.Fatal(frac.Err)
+	}
+	for i := range data {
+		if d := res.Seasonal[i] - frac.Seasonal[i]; math.Abs(d) > 1e-9 {
+			t.Fatalf("Seasonal[%d]: Decompose gave %v, DecomposeFractional gave %v", i, res.Seasonal[i], frac.Seasonal[i])
+		}
+		if d := res.Trend[i] - frac.Trend[i]; math.Abs(d) > 1e-9 {
+			t.Fatalf("Trend[%d]: Decompose gave %v, DecomposeFractional gave %v", i, res.Trend[i], frac.Trend[i])
+		}
+	}
+}
+
+func TestFMA(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	if got, want := fma(data, 3), ma(data, 3); len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("Expected fma with an integer period to be ma. Got %v", got)
+	}
+	got := fma(data, 2.5)
+	if len(got) != len(data)-2 {
+		t.Fatalf("Expected %d elements. Got %d", len(data)-2, len(got))
+	}
+	// a linear series is reproduced at the centre of the window
+	for i := range got {
+		if want := data[i+1]; math.Abs(got[i]-want) > 1e-12 {
+			t.Errorf("Expected %v. Got %v", want, got[i])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package plot_test
+
+import (
+	"math"
+	"os"
+
+	"github.com/chewxy/stl"
+	"github.com/chewxy/stl/plot"
+)
+
+func ExampleText() {
+	X := make([]float64, 48)
+	for i := range X {
+		X[i] = 10 + 0.1*float64(i) + 2*math.Sin(2*math.Pi*float64(i)/12) + 0.3*math.Cos(float64(i*i)) // with some noise
+	}
+	res := stl.Decompose(X, 12, 7, stl.Additive())
+	if err := plot.Text(os.Stdout, res, 40, 4); err != nil {
+		panic(err)
+	}
+	// Output:
+	// Data
+	//   16.17 ┤                  │      ╭─╮
+	//         │         ╭──╮   ╭─│─╮  ╭─╯ ╰─╮╭
+	//         │ ╭───╮  ╭╯  ╰╮╭─╯   ╰──╯     ╰╯
+	//   9.133 ┤─╯   ╰──╯    ╰╯
+	// Trend
+	//   14.57 ┤                         ╭─────
+	//         │               ╭─────────╯
+	//         │     ╭─────────╯
+	//   9.995 ┤─────╯
+	// Seasonal
+	//   2.298 ┤  ╭╮     ╭╮      ╭─╮     ╭─╮
+	//         │──╯╰╮   ╭╯╰─╮   ╭╯ ╰╮   ╭╯ │
+	//         │    ╰╮ ╭╯   ╰╮ ╭╯   ╰╮ ╭╯  ╰─╮╭
+	//  -2.113 ┤     ╰─╯     ╰─╯     ╰─╯     ╰╯
+	// Remainder
+	//  0.1976 ┤     │   ╭╮       ╭╮╭╮ │╭╮   ╭╮
+	//         │───╮╭│─╮╭╯╰─╮ ╭─╮ │╰╯│╭│╯│ ╭─╯│
+	//         │   ╰╯  ╰╯   │╭╯ │╭╯  ╰╯  ╰─╯  ╰
+	// -0.3081 ┤            ╰╯  ╰╯
+	//         └┬───────────┬────────────┬─────
+	//          0          20           40
+}
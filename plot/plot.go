@@ -0,0 +1,182 @@
+// Package plot draws the standard four panel chart of a STL decomposition: the data, the trend, the seasonal component and the remainder,
+// one above the other, with a shared x axis.
+package plot
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/chewxy/stl"
+	"github.com/pkg/errors"
+)
+
+// Opt is an option of a chart.
+type Opt func(*chart)
+
+// WithTimes labels the x axis with the times of the observations, instead of their indices.
+func WithTimes(ts []time.Time) Opt {
+	return func(c *chart) {
+		c.times = ts
+	}
+}
+
+// WithOutlierThreshold sets the robustness weight below which an observation is marked as an outlier in the images (see SVG and PNG).
+// The default is 0.5. Use 0 to mark none. The weights are those of a robust decomposition (see stl.WithRobustIter).
+func WithOutlierThreshold(t float64) Opt {
+	return func(c *chart) {
+		c.threshold = t
+	}
+}
+
+// defaultThreshold is the default robustness weight below which an observation is an outlier.
+const defaultThreshold = 0.5
+
+// panel is one of the components of the chart.
+type panel struct {
+	name     string
+	vals     []float64
+	min, max float64 // the range of the finite values
+}
+
+// chart is the decomposition to be drawn.
+type chart struct {
+	panels []panel
+	n      int
+	times  []time.Time
+
+	weights   []float64
+	threshold float64
+}
+
+func newChart(res stl.Result, opts []Opt) (*chart, error) {
+	if res.Err != nil {
+		return nil, errors.Wrap(res.Err, "Cannot plot a failed decomposition")
+	}
+	n := len(res.Data)
+	if n == 0 {
+		return nil, errors.New("Cannot plot an empty decomposition")
+	}
+	c := &chart{n: n, weights: res.Weights, threshold: defaultThreshold}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.times != nil && len(c.times) != n {
+		return nil, errors.Errorf("Expected %d times. Got %d", n, len(c.times))
+	}
+	if c.weights != nil && len(c.weights) != n {
+		return nil, errors.Errorf("Expected %d weights. Got %d", n, len(c.weights))
+	}
+
+	components := []struct {
+		name string
+		vals []float64
+	}{
+		{"Data", res.Data},
+		{"Trend", res.Trend},
+		{"Seasonal", res.Seasonal},
+		{"Remainder", res.Resid},
+	}
+	for _, comp := range components {
+		if len(comp.vals) != n {
+			return nil, errors.Errorf("Expected %d values of the %v. Got %d", n, comp.name, len(comp.vals))
+		}
+		p := panel{name: comp.name, vals: comp.vals, min: math.Inf(1), max: math.Inf(-1)}
+		for _, v := range comp.vals {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			p.min = math.Min(p.min, v)
+			p.max = math.Max(p.max, v)
+		}
+		c.panels = append(c.panels, p)
+	}
+	return c, nil
+}
+
+// outlier checks if the ith observation is an outlier.
+func (c *chart) outlier(i int) bool {
+	return c.weights != nil && c.weights[i] < c.threshold
+}
+
+// tick is a labelled position on the x axis, as an index into the series.
+type tick struct {
+	i     int
+	label string
+}
+
+// xTicks returns about n evenly spaced ticks of the x axis, labelled by the index of the observation or its time.
+// Times are labelled where the label changes (e.g. at the first observation of every year), so that the labels are exact.
+func (c *chart) xTicks(n int) []tick {
+	if n < 2 {
+		n = 2
+	}
+	var retVal []tick
+	if c.times != nil {
+		layout := c.timeLayout()
+		var all []tick
+		for i, t := range c.times {
+			label := t.Format(layout)
+			if len(all) == 0 || all[len(all)-1].label != label {
+				all = append(all, tick{i, label})
+			}
+		}
+		step := int(niceStep(float64(len(all)) / float64(n)))
+		if step < 1 {
+			step = 1
+		}
+		for j := 0; j < len(all); j += step {
+			retVal = append(retVal, all[j])
+		}
+		return retVal
+	}
+
+	step := niceStep(float64(c.n-1) / float64(n-1))
+	if step < 1 {
+		step = 1
+	}
+	for x := 0.0; x <= float64(c.n-1); x += step {
+		retVal = append(retVal, tick{int(x), formatValue(x)})
+	}
+	return retVal
+}
+
+// timeLayout picks the layout of the time labels by the span of the times.
+func (c *chart) timeLayout() string {
+	if c.times == nil {
+		return ""
+	}
+	span := c.times[c.n-1].Sub(c.times[0])
+	switch {
+	case span >= 3*365*24*time.Hour:
+		return "2006"
+	case span >= 60*24*time.Hour:
+		return "2006-01"
+	case span >= 3*24*time.Hour:
+		return "01-02"
+	}
+	return "15:04"
+}
+
+// niceStep rounds a step to the nearest of 1, 2 or 5 times a power of 10.
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(step)))
+	retVal := pow
+	for _, m := range []float64{2, 5, 10} {
+		if math.Abs(m*pow-step) < math.Abs(retVal-step) {
+			retVal = m * pow
+		}
+	}
+	return retVal
+}
+
+// formatValue formats a value of an axis: whole numbers as they are, and others with up to 4 significant digits.
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e9 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,233 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chewxy/stl"
+)
+
+func testResult(n int) stl.Result {
+	res := stl.Result{
+		Data:     make([]float64, n),
+		Trend:    make([]float64, n),
+		Seasonal: make([]float64, n),
+		Resid:    make([]float64, n),
+	}
+	for i := 0; i < n; i++ {
+		res.Trend[i] = float64(i)
+		res.Seasonal[i] = math.Sin(float64(i))
+		res.Data[i] = res.Trend[i] + res.Seasonal[i]
+	}
+	return res
+}
+
+func TestTextErrors(t *testing.T) {
+	short := testResult(10)
+	short.Resid = short.Resid[:5]
+	cases := []struct {
+		name          string
+		res           stl.Result
+		width, height int
+		opts          []Opt
+	}{
+		{"failed", stl.Result{Err: errors.New("failed")}, 80, 10, nil},
+		{"empty", stl.Result{}, 80, 10, nil},
+		{"lengths", short, 80, 10, nil},
+		{"narrow", testResult(10), 5, 10, nil},
+		{"flat", testResult(10), 80, 1, nil},
+		{"times", testResult(10), 80, 10, []Opt{WithTimes(make([]time.Time, 3))}},
+	}
+	for _, c := range cases {
+		if err := Text(new(bytes.Buffer), c.res, c.width, c.height, c.opts...); err == nil {
+			t.Errorf("%v: expected an error", c.name)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	res := testResult(1000)
+	res.Resid[500] = 100 // a spike, which must not be lost when the columns are downsampled
+	res.Resid[10] = math.NaN()
+	ts := make([]time.Time, len(res.Data))
+	for i := range ts {
+		ts[i] = time.Date(2000, 1, 1+i, 0, 0, 0, 0, time.UTC)
+	}
+
+	var buf bytes.Buffer
+	if err := Text(&buf, res, 60, 6, WithTimes(ts)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 4*7+2 {
+		t.Fatalf("Expected %d lines. Got %d:\n%s", 4*7+2, len(lines), buf.String())
+	}
+	for _, l := range lines {
+		if n := len([]rune(l)); n > 60 {
+			t.Errorf("Line is %d characters wide: %q", n, l)
+		}
+	}
+
+	// the remainder is constant apart from the spike
+	remainder := lines[3*7 : 4*7]
+	if remainder[0] != "Remainder" || !strings.HasPrefix(strings.TrimSpace(remainder[1]), "100 ┤") {
+		t.Errorf("Expected the remainder to be labelled from 0 to 100. Got\n%s", strings.Join(remainder, "\n"))
+	}
+	if !strings.ContainsRune(remainder[1], '│') {
+		t.Errorf("Expected the spike to be drawn. Got\n%s", strings.Join(remainder, "\n"))
+	}
+	if labels := lines[len(lines)-1]; !strings.Contains(labels, "2000-01") || !strings.Contains(labels, "2002") {
+		t.Errorf("Expected the x axis to be labelled with months. Got %q", labels)
+	}
+
+	// constant components are drawn in the middle of their panels
+	for i := range res.Data {
+		res.Data[i] = 5
+	}
+	buf.Reset()
+	if err := Text(&buf, res, 60, 5); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(strings.TrimSpace(lines[3]), "5 ┤───") {
+		t.Errorf("Expected a constant line in the middle. Got\n%s", strings.Join(lines[:6], "\n"))
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	cases := map[float64]float64{0: 1, 1: 1, 1.4: 1, 1.6: 2, 3.4: 2, 3.6: 5, 23.5: 20, 80: 100, 0.3: 0.2}
+	for step, expected := range cases {
+		if got := niceStep(step); math.Abs(got-expected) > 1e-12 {
+			t.Errorf("niceStep(%v): expected %v. Got %v", step, expected, got)
+		}
+	}
+}
+
+func robustResult(t *testing.T) stl.Result {
+	r := rand.New(rand.NewSource(1337))
+	X := make([]float64, 120)
+	for i := range X {
+		X[i] = 10 + 0.1*float64(i) + 2*math.Sin(2*math.Pi*float64(i)/12) + 0.1*r.NormFloat64()
+	}
+	X[50] += 30
+	res := stl.Decompose(X, 12, 7, stl.Additive(), stl.WithRobustIter(2))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	return res
+}
+
+func TestSVG(t *testing.T) {
+	res := robustResult(t)
+	ts := make([]time.Time, len(res.Data))
+	for i := range ts {
+		ts[i] = time.Date(2000, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var buf bytes.Buffer
+	if err := SVG(&buf, res, 640, 480, WithTimes(ts)); err != nil {
+		t.Fatal(err)
+	}
+	elements := make(map[string]int)
+	var texts []string
+	d := xml.NewDecoder(&buf)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			elements[tok.Name.Local]++
+		case xml.CharData:
+			if s := strings.TrimSpace(string(tok)); s != "" {
+				texts = append(texts, s)
+			}
+		}
+	}
+	if elements["polyline"] != 4 {
+		t.Errorf("Expected a line per panel. Got %d", elements["polyline"])
+	}
+	if elements["circle"] != 2 {
+		t.Errorf("Expected the outlier to be marked on the data and the remainder. Got %d markers", elements["circle"])
+	}
+	all := strings.Join(texts, " ")
+	for _, s := range []string{"Data", "Trend", "Seasonal", "Remainder", "2002"} {
+		if !strings.Contains(all, s) {
+			t.Errorf("Expected the label %q. Got %v", s, texts)
+		}
+	}
+
+	buf.Reset()
+	if err := SVG(&buf, res, 640, 480, WithOutlierThreshold(0)); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<circle"); n != 0 {
+		t.Errorf("Expected no markers with a threshold of 0. Got %d", n)
+	}
+
+	if err := SVG(&buf, res, 50, 50); err == nil {
+		t.Error("Expected an error for a tiny image")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	res := robustResult(t)
+	res.Trend[60] = math.NaN()
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, res, 400, 300); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 300 {
+		t.Fatalf("Expected a 400x300 image. Got %v", b)
+	}
+	colors := make(map[color.RGBA]int)
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			colors[color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}]++
+		}
+	}
+	for name, c := range map[string]color.RGBA{"line": lineColor, "outlier": outlierColor, "label": inkColor, "grid": gridColor} {
+		if colors[c] == 0 {
+			t.Errorf("Expected some %v pixels", name)
+		}
+	}
+	// two markers of about π·3² pixels each
+	if n := colors[outlierColor]; n < 40 || n > 70 {
+		t.Errorf("Expected two outlier markers. Got %d pixels", n)
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	for _, s := range []string{"0123456789", "ABCDEFGHIJKLMNOPQRSTUVWXYZ", "-+.:"} {
+		for _, r := range s {
+			g, ok := glyphs[r]
+			if !ok {
+				t.Errorf("Missing glyph %q", r)
+			}
+			for _, row := range g {
+				if row > 0x1f {
+					t.Errorf("Glyph %q is wider than 5 pixels", r)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package plot
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/chewxy/stl"
+)
+
+// PNG draws the chart of the decomposition as a PNG image of width by height pixels. It is laid out as SVG is.
+// The labels are drawn in a built in 5x7 bitmap font, in upper case.
+func PNG(w io.Writer, res stl.Result, width, height int, opts ...Opt) error {
+	c, err := newChart(res, opts)
+	if err != nil {
+		return err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	if err = c.draw(&pngCanvas{img}, width, height); err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// pngCanvas rasterizes the chart onto an image.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+// line draws a line with Bresenham's algorithm.
+func (cv *pngCanvas) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	ax, ay := int(math.Round(x0)), int(math.Round(y0))
+	bx, by := int(math.Round(x1)), int(math.Round(y1))
+	dx, dy := abs(bx-ax), -abs(by-ay)
+	sx, sy := 1, 1
+	if ax > bx {
+		sx = -1
+	}
+	if ay > by {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		cv.img.SetRGBA(ax, ay, c)
+		if ax == bx && ay == by {
+			return
+		}
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			ax += sx
+		} else {
+			e += dx
+			ay += sy
+		}
+	}
+}
+
+func (cv *pngCanvas) polyline(xs, ys []float64, c color.RGBA) {
+	for i := 1; i < len(xs); i++ {
+		cv.line(xs[i-1], ys[i-1], xs[i], ys[i], c)
+	}
+}
+
+func (cv *pngCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	cv.line(x, y, x+w, y, c)
+	cv.line(x+w, y, x+w, y+h, c)
+	cv.line(x+w, y+h, x, y+h, c)
+	cv.line(x, y+h, x, y, c)
+}
+
+func (cv *pngCanvas) marker(x, y float64, c color.RGBA) {
+	cx, cy := int(math.Round(x)), int(math.Round(y))
+	for dy := -markerSize; dy <= markerSize; dy++ {
+		for dx := -markerSize; dx <= markerSize; dx++ {
+			if dx*dx+dy*dy <= markerSize*markerSize {
+				cv.img.SetRGBA(cx+dx, cy+dy, c)
+			}
+		}
+	}
+}
+
+// text draws a label with its baseline at y. Characters that are not in the font are left blank.
+func (cv *pngCanvas) text(x, y float64, s string, a anchor, c color.RGBA) {
+	s = strings.ToUpper(s)
+	w := float64(len(s)*charWidth - 1)
+	switch a {
+	case middle:
+		x -= w / 2
+	case end:
+		x -= w
+	}
+	left, top := int(math.Round(x)), int(math.Round(y))-charHeight+1
+	for k, r := range []rune(s) {
+		g, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		for row, bits := range g {
+			for col := 0; col < 5; col++ {
+				if bits&(0x10>>uint(col)) != 0 {
+					cv.img.SetRGBA(left+k*charWidth+col, top+row, c)
+				}
+			}
+		}
+	}
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,154 @@
+package plot
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// the dimensions of the layout of the images, in pixels
+const (
+	charWidth  = 6 // the width of a character of a label, including the space after it. The bitmap font of PNG is 5x7
+	charHeight = 7
+	margin     = 10 // around the image
+	panelGap   = 8  // between panels
+	tickLength = 4
+	markerSize = 3 // the radius of the outlier markers
+)
+
+var (
+	inkColor     = color.RGBA{0x33, 0x33, 0x33, 0xff} // frames and labels
+	gridColor    = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	lineColor    = color.RGBA{0x1f, 0x4e, 0x79, 0xff}
+	outlierColor = color.RGBA{0xd6, 0x27, 0x28, 0xff}
+)
+
+// anchor is the horizontal alignment of a label.
+type anchor int
+
+const (
+	start anchor = iota
+	middle
+	end
+)
+
+// canvas is what the chart is drawn on, by SVG and PNG.
+type canvas interface {
+	line(x0, y0, x1, y1 float64, c color.RGBA)
+	polyline(xs, ys []float64, c color.RGBA)
+	rect(x, y, w, h float64, c color.RGBA) // the outline of a rectangle
+	marker(x, y float64, c color.RGBA)     // a filled circle, markerSize in radius
+	text(x, y float64, s string, a anchor, c color.RGBA)
+}
+
+// yTick is a labelled position on the y axis of a panel.
+type yTick struct {
+	v     float64
+	label string
+}
+
+// yTicks returns the ticks of the y axis of a panel, at round values, and the range of the axis.
+func (p panel) yTicks() (ticks []yTick, lo, hi float64) {
+	lo, hi = p.min, p.max
+	switch {
+	case math.IsInf(lo, 0):
+		// no finite values
+		return nil, 0, 1
+	case lo == hi:
+		pad := math.Max(math.Abs(lo)*0.1, 1)
+		lo, hi = lo-pad, hi+pad
+	}
+	step := niceStep((hi - lo) / 4)
+	for v := math.Ceil(lo/step) * step; v <= hi+step*1e-9; v += step {
+		if math.Abs(v) < step*1e-9 {
+			v = 0
+		}
+		ticks = append(ticks, yTick{v, formatValue(v)})
+	}
+	return ticks, lo, hi
+}
+
+// draw draws the chart on a canvas of the given size in pixels.
+func (c *chart) draw(cv canvas, width, height int) error {
+	type axis struct {
+		ticks  []yTick
+		lo, hi float64
+	}
+	axes := make([]axis, len(c.panels))
+	var lw int
+	for k, p := range c.panels {
+		ticks, lo, hi := p.yTicks()
+		axes[k] = axis{ticks, lo, hi}
+		for _, t := range ticks {
+			if len(t.label) > lw {
+				lw = len(t.label)
+			}
+		}
+	}
+
+	left := float64(margin + lw*charWidth + tickLength + 2)
+	right := float64(width - margin)
+	top := float64(margin)
+	bottom := float64(height - margin - tickLength - charHeight - 4) // room for the labels of the x axis
+	ph := (bottom-top-panelGap*float64(len(c.panels)-1))/float64(len(c.panels)) - charHeight - 4
+	if right-left < 20 || ph < 20 {
+		return errors.Errorf("An image of %dx%d is too small for the chart", width, height)
+	}
+
+	x := func(i int) float64 {
+		if c.n == 1 {
+			return (left + right) / 2
+		}
+		return left + float64(i)*(right-left)/float64(c.n-1)
+	}
+	xTicks := c.xTicks(int((right - left) / 80))
+
+	for k, p := range c.panels {
+		// each panel is titled, above its frame
+		ptop := top + float64(k)*(ph+charHeight+4+panelGap) + charHeight + 4
+		pbottom := ptop + ph
+		a := axes[k]
+		y := func(v float64) float64 { return pbottom - (v-a.lo)/(a.hi-a.lo)*ph }
+
+		cv.text(left, ptop-4, p.name, start, inkColor)
+		for _, t := range xTicks {
+			cv.line(x(t.i), ptop, x(t.i), pbottom, gridColor)
+		}
+		for _, t := range a.ticks {
+			ty := y(t.v)
+			cv.line(left, ty, right, ty, gridColor)
+			cv.line(left-tickLength, ty, left, ty, inkColor)
+			cv.text(left-tickLength-2, ty+charHeight/2, t.label, end, inkColor)
+		}
+
+		// the line is broken at missing values
+		var xs, ys []float64
+		for i, v := range p.vals {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				cv.polyline(xs, ys, lineColor)
+				xs, ys = xs[:0], ys[:0]
+				continue
+			}
+			xs, ys = append(xs, x(i)), append(ys, y(v))
+		}
+		cv.polyline(xs, ys, lineColor)
+		if k == 0 || k == len(c.panels)-1 {
+			// outliers are marked on the data and the remainder
+			for i, v := range p.vals {
+				if c.outlier(i) && !math.IsNaN(v) && !math.IsInf(v, 0) {
+					cv.marker(x(i), y(v), outlierColor)
+				}
+			}
+		}
+		cv.rect(left, ptop, right-left, ph, inkColor)
+
+		if k == len(c.panels)-1 {
+			for _, t := range xTicks {
+				cv.line(x(t.i), pbottom, x(t.i), pbottom+tickLength, inkColor)
+				cv.text(x(t.i), pbottom+tickLength+charHeight+2, t.label, middle, inkColor)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package plot
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/chewxy/stl"
+)
+
+// SVG draws the chart of the decomposition as an SVG image of width by height pixels.
+//
+// The panels share the x axis, which is labelled with the times of the observations if they are given (see WithTimes).
+// Observations whose robustness weight is below the outlier threshold (see WithOutlierThreshold) are marked on the data and the remainder.
+func SVG(w io.Writer, res stl.Result, width, height int, opts ...Opt) error {
+	c, err := newChart(res, opts)
+	if err != nil {
+		return err
+	}
+	cv := &svgCanvas{}
+	if err = c.draw(cv, width, height); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(bw, `<g font-family="monospace" font-size="10" stroke-width="1" fill="none">`+"\n")
+	bw.WriteString(cv.String())
+	bw.WriteString("</g>\n</svg>\n")
+	return bw.Flush()
+}
+
+// svgCanvas writes the elements of an SVG image.
+type svgCanvas struct {
+	strings.Builder
+}
+
+func hex(c color.RGBA) string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+
+func (cv *svgCanvas) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	fmt.Fprintf(cv, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", x0, y0, x1, y1, hex(c))
+}
+
+func (cv *svgCanvas) polyline(xs, ys []float64, c color.RGBA) {
+	if len(xs) < 2 {
+		return
+	}
+	cv.WriteString(`<polyline points="`)
+	for i := range xs {
+		if i > 0 {
+			cv.WriteByte(' ')
+		}
+		fmt.Fprintf(cv, "%.1f,%.1f", xs[i], ys[i])
+	}
+	fmt.Fprintf(cv, `" stroke="%s"/>`+"\n", hex(c))
+}
+
+func (cv *svgCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	fmt.Fprintf(cv, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" stroke="%s"/>`+"\n", x, y, w, h, hex(c))
+}
+
+func (cv *svgCanvas) marker(x, y float64, c color.RGBA) {
+	fmt.Fprintf(cv, `<circle cx="%.1f" cy="%.1f" r="%d" fill="%s"/>`+"\n", x, y, markerSize, hex(c))
+}
+
+func (cv *svgCanvas) text(x, y float64, s string, a anchor, c color.RGBA) {
+	anchors := [...]string{start: "start", middle: "middle", end: "end"}
+	fmt.Fprintf(cv, `<text x="%.1f" y="%.1f" text-anchor="%s" fill="%s">`, x, y, anchors[a], hex(c))
+	xml.EscapeText(cv, []byte(s))
+	cv.WriteString("</text>\n")
+}
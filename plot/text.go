@@ -0,0 +1,169 @@
+package plot
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/chewxy/stl"
+	"github.com/pkg/errors"
+)
+
+// Text draws the chart of the decomposition as text, with box drawing characters, for terminals.
+//
+// The chart is width characters wide, including the labels of the y axes, and each panel is height lines high, excluding its title.
+// When there are more observations than columns, each column shows the range of the observations that fall in it, so spikes are not lost.
+func Text(w io.Writer, res stl.Result, width, height int, opts ...Opt) error {
+	c, err := newChart(res, opts)
+	if err != nil {
+		return err
+	}
+	if height < 2 {
+		return errors.Errorf("Expected a height of at least 2. Got %d", height)
+	}
+
+	labels := make([][]string, len(c.panels)) // the labels of the y axis of each panel, by row
+	var lw int
+	for k, p := range c.panels {
+		labels[k] = yLabels(p, height)
+		for _, l := range labels[k] {
+			if len(l) > lw {
+				lw = len(l)
+			}
+		}
+	}
+	cols := width - lw - 2
+	if cols < 2 {
+		return errors.Errorf("A width of %d leaves no room for the chart. Expected at least %d", width, lw+4)
+	}
+	if cols > c.n {
+		cols = c.n
+	}
+
+	bw := bufio.NewWriter(w)
+	for k, p := range c.panels {
+		bw.WriteString(p.name + "\n")
+		grid := p.draw(cols, height)
+		for r := height - 1; r >= 0; r-- {
+			axis := " │"
+			if labels[k][r] != "" {
+				axis = " ┤"
+			}
+			bw.WriteString(strings.Repeat(" ", lw-len(labels[k][r])) + labels[k][r] + axis)
+			bw.WriteString(strings.TrimRight(string(grid[r]), " ") + "\n")
+		}
+	}
+
+	// the shared x axis
+	ticks := c.xTicks(cols / 10)
+	axis := []rune(strings.Repeat("─", cols))
+	tickLabels := []rune(strings.Repeat(" ", cols+lw+2))
+	end := -1 // the end of the last label written
+	for _, t := range ticks {
+		col := t.i * cols / c.n
+		axis[col] = '┬'
+		start := lw + 2 + col - len(t.label)/2
+		if start < 0 {
+			start = 0
+		}
+		if start <= end || start+len(t.label) > len(tickLabels) {
+			continue
+		}
+		copy(tickLabels[start:], []rune(t.label))
+		end = start + len(t.label)
+	}
+	bw.WriteString(strings.Repeat(" ", lw) + " └" + string(axis) + "\n")
+	bw.WriteString(strings.TrimRight(string(tickLabels), " ") + "\n")
+	return bw.Flush()
+}
+
+// yLabels returns the labels of the y axis of the panel by row: the maximum at the top, the minimum at the bottom, and the middle if there is room.
+func yLabels(p panel, height int) []string {
+	retVal := make([]string, height)
+	switch {
+	case math.IsInf(p.min, 0):
+		// no finite values
+	case p.min == p.max:
+		retVal[(height-1)/2] = formatValue(p.min)
+	default:
+		retVal[0] = formatValue(p.min)
+		retVal[height-1] = formatValue(p.max)
+		if height >= 5 {
+			mid := (height - 1) / 2
+			retVal[mid] = formatValue(p.min + (p.max-p.min)*float64(mid)/float64(height-1))
+		}
+	}
+	return retVal
+}
+
+// row returns the row (from the bottom) of a value in a panel height rows high.
+func (p panel) row(v float64, height int) int {
+	if p.max == p.min {
+		return (height - 1) / 2
+	}
+	return int(math.Round((v - p.min) / (p.max - p.min) * float64(height-1)))
+}
+
+// draw draws the panel on a grid of cols columns and height rows, indexed by row from the bottom.
+// The observations are split evenly between the columns, and each column is drawn from where the previous one left off to its last observation.
+func (p panel) draw(cols, height int) [][]rune {
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", cols))
+	}
+	n := len(p.vals)
+	prev := -1 // the row of the last value of the previous column
+	for col := 0; col < cols; col++ {
+		lo, hi, last := height, -1, -1
+		for i := col * n / cols; i < (col+1)*n/cols; i++ {
+			v := p.vals[i]
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			r := p.row(v, height)
+			if last < 0 && prev < 0 {
+				prev = r
+			}
+			last = r
+			if r < lo {
+				lo = r
+			}
+			if r > hi {
+				hi = r
+			}
+		}
+		if last < 0 {
+			// nothing to draw. The line is broken
+			prev = -1
+			continue
+		}
+		a, b := prev, last
+		if a < lo {
+			lo = a
+		}
+		if a > hi {
+			hi = a
+		}
+		switch {
+		case lo == hi:
+			grid[a][col] = '─'
+		case lo < a && lo < b || hi > a && hi > b:
+			// the column has a spike beyond the ends of the line
+			for r := lo; r <= hi; r++ {
+				grid[r][col] = '│'
+			}
+		default:
+			for r := lo + 1; r < hi; r++ {
+				grid[r][col] = '│'
+			}
+			if a < b {
+				grid[a][col], grid[b][col] = '╯', '╭'
+			} else {
+				grid[a][col], grid[b][col] = '╮', '╰'
+			}
+		}
+		prev = last
+	}
+	return grid
+}
@@ -0,0 +1,164 @@
+package stl
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/chewxy/stl/loess"
+	"github.com/pkg/errors"
+)
+
+// WidthScore is the score of a pair of candidate seasonal and trend widths.
+type WidthScore struct {
+	Seasonal int
+	Trend    int
+
+	RSS float64 // residual sum of squares of the remainder (in the transformed scale of the model)
+	ENP float64 // a rough approximation of the equivalent number of parameters of the decomposition. See SelectWidths
+	GCV float64 // generalized cross validation score. Lower is better
+	Err error   // the error encountered when decomposing with these widths, if any
+}
+
+// Selection is the result of SelectWidths.
+type Selection struct {
+	// Seasonal is the chosen seasonal width, to be passed to Decompose
+	Seasonal int
+	// Trend is the chosen trend width
+	Trend int
+
+	// Scores is the table of the scores of all the candidates, sorted by seasonal and then trend width.
+	Scores []WidthScore
+}
+
+// Opts returns the options to use with Decompose in order to use the selected trend width.
+// They only set the width of the trend smoother, so they must follow any trend configuration (see WithTrendConfig) among the options.
+func (s Selection) Opts() []Opt {
+	return []Opt{withTrendWidth(s.Trend)}
+}
+
+// DefaultSeasonalWidths are the seasonal widths tried by SelectWidths when none are provided.
+var DefaultSeasonalWidths = []int{7, 9, 11, 13, 15, 21, 35}
+
+// SelectWidths selects the seasonal and trend widths by generalized cross validation (GCV) of the remainder.
+//
+// Every combination of the candidate seasonal and trend widths is decomposed (concurrently, across GOMAXPROCS goroutines),
+// and the one with the lowest GCV score
+//
+//	GCV = (RSS/n) / (1 - ENP/n)^2
+//
+// is chosen. The equivalent number of parameters (ENP) of the decomposition, the trace of its operator matrix, is roughly approximated
+// by n times the sum of the leverages of local linear seasonal and trend smoothers at an interior point. The approximation ignores the interaction
+// of the smoothers over the iterations, the low-pass filter, the regressions, kernels and jumps of the configurations, the edges, and the robustness weights.
+// It is only meant to rank the candidates, not as an estimate of the degrees of freedom of the decomposition.
+//
+// If no seasonal widths are provided, DefaultSeasonalWidths is used. If no trend widths are provided, the default trend width
+// for each seasonal width is used (see DefaultTrend). X is not modified. The options are applied to every candidate decomposition,
+// which then only sets the width of the trend smoother, whether it is configured by the options or by default.
+func SelectWidths(X []float64, periodicity int, m ModelType, seasonal, trend []int, opts ...Opt) (Selection, error) {
+	if periodicity < 2 {
+		return Selection{}, errors.Errorf("Periodicity must be greater than 2")
+	}
+	if len(seasonal) == 0 {
+		seasonal = DefaultSeasonalWidths
+	}
+
+	var scores []WidthScore
+	for _, sw := range seasonal {
+		if len(trend) == 0 {
+			scores = append(scores, WidthScore{Seasonal: sw, Trend: trendWidth(periodicity, sw)})
+			continue
+		}
+		for _, tw := range trend {
+			scores = append(scores, WidthScore{Seasonal: sw, Trend: tw})
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i := range scores {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(sc *WidthScore) {
+			defer func() { <-sem; wg.Done() }()
+			sc.score(X, periodicity, m, opts)
+		}(&scores[i])
+	}
+	wg.Wait()
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Seasonal != scores[j].Seasonal {
+			return scores[i].Seasonal < scores[j].Seasonal
+		}
+		return scores[i].Trend < scores[j].Trend
+	})
+
+	retVal := Selection{Scores: scores}
+	best := math.Inf(1)
+	for _, sc := range scores {
+		if sc.Err == nil && sc.GCV < best {
+			best = sc.GCV
+			retVal.Seasonal = sc.Seasonal
+			retVal.Trend = sc.Trend
+		}
+	}
+	if math.IsInf(best, 1) {
+		return retVal, errors.New("No candidate widths could be scored")
+	}
+	return retVal, nil
+}
+
+// score decomposes a copy of X with the candidate widths, and computes its GCV score.
+func (sc *WidthScore) score(X []float64, periodicity int, m ModelType, opts []Opt) {
+	if sc.Seasonal < 1 || sc.Trend < 1 {
+		sc.Err = errors.Errorf("Invalid widths (%d, %d)", sc.Seasonal, sc.Trend)
+		return
+	}
+	data := make([]float64, len(X))
+	copy(data, X)
+	data = m.Fwd(data)
+
+	opts = append(opts[:len(opts):len(opts)], withTrendWidth(sc.Trend))
+	s := newState(data, periodicity, sc.Seasonal, opts...)
+	if sc.Err = s.iterate(); sc.Err != nil {
+		return
+	}
+
+	n := float64(len(data))
+	for _, r := range s.Resid {
+		sc.RSS += r * r
+	}
+	sc.ENP = n * (leverage(s.sConf.Width) + leverage(s.tConf.Width))
+	if sc.ENP >= n {
+		sc.GCV = math.Inf(1)
+		return
+	}
+	d := 1 - sc.ENP/n
+	sc.GCV = sc.RSS / n / (d * d)
+}
+
+// leverage is the leverage of the local linear loess smoother of the given width at an interior point.
+// It is the diagonal element of the smoother matrix - the weight of a point on its own fitted value.
+func leverage(width int) float64 {
+	if width <= 1 {
+		return 1
+	}
+	// width is made odd so that the interior point is centered
+	width |= 1
+	centre := width / 2
+	e := make([]float64, width)
+	e[centre] = 1
+	l, err := loess.Regress(loess.New(width, e), loess.Linear, float64(centre), 0, float64(width-1))
+	if err != nil {
+		return 1
+	}
+	return l
+}
+
+// withTrendWidth sets the width of the trend smoother, keeping the rest of the trend configuration.
+func withTrendWidth(width int) Opt {
+	return func(s *state) {
+		s.tConf.Width = width
+	}
+}
@@ -0,0 +1,112 @@
+package stl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chewxy/stl/loess"
+)
+
+func TestSelectWidths(t *testing.T) {
+	data := loadCO2(t)
+	orig := make([]float64, len(data))
+	copy(orig, data)
+
+	sel, err := SelectWidths(data, 12, Multiplicative(), []int{7, 35}, []int{15, 23, 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Scores) != 6 {
+		t.Fatalf("Expected 6 scores. Got %d", len(sel.Scores))
+	}
+	for i := range orig {
+		if data[i] != orig[i] {
+			t.Fatalf("Data was modified at %d", i)
+		}
+	}
+
+	best := math.Inf(1)
+	for _, sc := range sel.Scores {
+		if sc.Err != nil {
+			t.Errorf("Widths (%d, %d): %v", sc.Seasonal, sc.Trend, sc.Err)
+			continue
+		}
+		if sc.ENP <= 0 || sc.RSS <= 0 {
+			t.Errorf("Widths (%d, %d): unexpected ENP %v and RSS %v", sc.Seasonal, sc.Trend, sc.ENP, sc.RSS)
+		}
+		best = math.Min(best, sc.GCV)
+	}
+	for _, sc := range sel.Scores {
+		if sc.Seasonal == sel.Seasonal && sc.Trend == sel.Trend && sc.GCV != best {
+			t.Errorf("Expected the selection to have the lowest GCV")
+		}
+	}
+
+	res := Decompose(data, 12, sel.Seasonal, Additive(), sel.Opts()...)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	// default candidates
+	sel, err = SelectWidths(orig, 12, Additive(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Scores) != len(DefaultSeasonalWidths) {
+		t.Errorf("Expected %d scores. Got %d", len(DefaultSeasonalWidths), len(sel.Scores))
+	}
+}
+
+func TestSelectWidthsTrendConfig(t *testing.T) {
+	data := loadCO2(t)
+	conf := DefaultTrend(12, 7)
+	conf.Kernel = loess.Gaussian
+	conf.Fn = loess.Constant
+	conf.Jump = 3
+
+	sel, err := SelectWidths(data, 12, Additive(), []int{7}, []int{15, 23}, WithTrendConfig(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range sel.Scores {
+		// the candidate trend width replaces the width of the trend configuration, and nothing else
+		expected := conf
+		expected.Width = sc.Trend
+		res := Decompose(append([]float64(nil), data...), 12, 7, Additive(), WithTrendConfig(expected))
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		var rss float64
+		for _, r := range res.Resid {
+			rss += r * r
+		}
+		if math.Abs(rss-sc.RSS) > 1e-9*rss {
+			t.Errorf("Trend width %d: expected the RSS of the configured trend smoother %v. Got %v", sc.Trend, rss, sc.RSS)
+		}
+
+		opts := append([]Opt{WithTrendConfig(conf)}, Selection{Seasonal: 7, Trend: sc.Trend}.Opts()...)
+		res = Decompose(append([]float64(nil), data...), 12, 7, Additive(), opts...)
+		var got float64
+		for _, r := range res.Resid {
+			got += r * r
+		}
+		if got != rss {
+			t.Errorf("Trend width %d: expected Opts to reproduce the scored decomposition", sc.Trend)
+		}
+	}
+}
+
+func TestLeverage(t *testing.T) {
+	prev := leverage(1)
+	if prev != 1 {
+		t.Errorf("Expected a leverage of 1 for a width of 1. Got %v", prev)
+	}
+	// a width of 3 gives no weight to the neighbours, as they are at the edge of the tricube kernel
+	for _, w := range []int{5, 7, 15, 35} {
+		l := leverage(w)
+		if l <= 0 || l >= prev {
+			t.Errorf("Expected the leverage to decrease with width. Width %d has leverage %v", w, l)
+		}
+		prev = l
+	}
+}
@@ -0,0 +1,259 @@
+package stl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Unit is a calendar unit by which a Series is stepped. Calendar units do not have a fixed duration
+// (months have different lengths, days may be 23 or 25 hours long across daylight savings changes),
+// so they are stepped with time.Time.AddDate instead of time.Time.Add.
+type Unit int
+
+const (
+	// Fixed indicates that the series is stepped by a fixed time.Duration (Series.Step)
+	Fixed Unit = iota
+	Day
+	Week
+	Month
+	Quarter
+	Year
+)
+
+func (u Unit) String() string {
+	switch u {
+	case Fixed:
+		return "Fixed"
+	case Day:
+		return "Day"
+	case Week:
+		return "Week"
+	case Month:
+		return "Month"
+	case Quarter:
+		return "Quarter"
+	case Year:
+		return "Year"
+	}
+	return fmt.Sprintf("Unit(%d)", int(u))
+}
+
+// Series is a regularly spaced time series. The i-th value was observed at Start + i steps.
+type Series struct {
+	Start time.Time
+	// Step is the time between observations. It is only used when Unit is Fixed.
+	Step time.Duration
+	// Unit is the calendar unit between observations.
+	Unit   Unit
+	Values []float64
+
+	// Period, if non-zero, overrides the periodicity inferred from the step of the series.
+	Period int
+}
+
+const day = 24 * time.Hour
+
+// unit normalizes the unit of the series: fixed steps of exactly a day or a week are treated as their calendar equivalents.
+func (s Series) unit() Unit {
+	if s.Unit != Fixed {
+		return s.Unit
+	}
+	switch s.Step {
+	case day:
+		return Day
+	case 7 * day:
+		return Week
+	}
+	return Fixed
+}
+
+// Time returns the time of the i-th observation.
+func (s Series) Time(i int) time.Time {
+	switch s.Unit {
+	case Fixed:
+		return s.Start.Add(time.Duration(i) * s.Step)
+	case Day:
+		return s.Start.AddDate(0, 0, i)
+	case Week:
+		return s.Start.AddDate(0, 0, 7*i)
+	case Month:
+		return s.addMonths(i)
+	case Quarter:
+		return s.addMonths(3 * i)
+	case Year:
+		return s.addMonths(12 * i)
+	}
+	panic(fmt.Sprintf("Unknown unit %v", s.Unit))
+}
+
+// addMonths returns the start of the series, n months later. Unlike time.Time.AddDate, a day that is past the end of a shorter month
+// is clamped to the last day of that month, rather than overflowing into the next: a monthly series that starts on January 31
+// continues on February 28 (or 29), March 31, April 30, and so on.
+func (s Series) addMonths(n int) time.Time {
+	y, m, d := s.Start.Date()
+	hh, mm, ss := s.Start.Clock()
+	first := time.Date(y, m+time.Month(n), 1, hh, mm, ss, s.Start.Nanosecond(), s.Start.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, hh, mm, ss, s.Start.Nanosecond(), s.Start.Location())
+}
+
+// Times returns the times of all the observations in the series.
+func (s Series) Times() []time.Time {
+	retVal := make([]time.Time, len(s.Values))
+	for i := range retVal {
+		retVal[i] = s.Time(i)
+	}
+	return retVal
+}
+
+// Periodicity returns the number of observations in a seasonal cycle. Unless overridden by Period, it is inferred from the frequency of the series:
+//
+//	sub-daily steps: one day (e.g. 24 for hourly, 288 for 5-minutely data)
+//	daily: 7 (one week)
+//	weekly: 52 (one year)
+//	monthly: 12 (one year)
+//	quarterly: 4 (one year)
+//
+// Yearly series, and series whose fixed step does not evenly divide a day have no natural seasonal cycle.
+func (s Series) Periodicity() (int, error) {
+	if s.Period != 0 {
+		return s.Period, nil
+	}
+	switch s.unit() {
+	case Fixed:
+		if s.Step <= 0 {
+			return 0, errors.Errorf("Cannot infer periodicity from a step of %v", s.Step)
+		}
+		if s.Step > day || day%s.Step != 0 {
+			return 0, errors.Errorf("Cannot infer periodicity: a step of %v does not evenly divide a day", s.Step)
+		}
+		return int(day / s.Step), nil
+	case Day:
+		return 7, nil
+	case Week:
+		return 52, nil
+	case Month:
+		return 12, nil
+	case Quarter:
+		return 4, nil
+	}
+	return 0, errors.Errorf("Cannot infer periodicity of a series stepped by %v", s.Unit)
+}
+
+// YearPeriod returns the (usually non-integer) number of observations in a year, for use with DecomposeFractional.
+// For example, weekly data has a period of 365.25/7 ≈ 52.18 observations, and daily data has a period of 365.25 observations.
+func (s Series) YearPeriod() (float64, error) {
+	const year = 365.25
+	switch s.unit() {
+	case Fixed:
+		if s.Step <= 0 {
+			return 0, errors.Errorf("Cannot infer the yearly period from a step of %v", s.Step)
+		}
+		return year * float64(day) / float64(s.Step), nil
+	case Day:
+		return year, nil
+	case Week:
+		return year / 7, nil
+	case Month:
+		return 12, nil
+	case Quarter:
+		return 4, nil
+	}
+	return 0, errors.Errorf("Cannot infer the yearly period of a series stepped by %v", s.Unit)
+}
+
+// phases returns the seasonal phase of every observation, and a label for each phase.
+//
+// The phase of the ith observation is that of the first observation plus i, modulo the periodicity: it is the cycle-subseries
+// the observation is smoothed in. For the inferred periodicities the phase of the first observation is aligned to the calendar -
+// the phase of a monthly series is the month of the year, the phase of a daily series is the day of the week, and so on.
+// When the periodicity is overridden, the first observation is phase 0.
+//
+// A weekly series starts at the phase of its ISO week (week 53 shares the phase of week 52), but years have 52 or 53 ISO weeks,
+// so its phases fall a week behind the ISO weeks in every year with 53 of them. Weekly data whose seasonal component has to stay
+// aligned to the calendar should be decomposed by DecomposeFractional with the YearPeriod of the series instead.
+func (s Series) phases(periodicity int) (phase []int, labels []string) {
+	phase = make([]int, len(s.Values))
+	labels = make([]string, periodicity)
+	var first int // the phase of the first observation
+
+	switch {
+	case s.Period != 0:
+		for i := range labels {
+			labels[i] = fmt.Sprintf("%d", i)
+		}
+	case s.unit() == Fixed:
+		h, m, sec := s.Start.Clock()
+		tod := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second + time.Duration(s.Start.Nanosecond())
+		first = int(tod/s.Step) % periodicity
+		format := "15:04"
+		if s.Step%time.Minute != 0 {
+			format = "15:04:05"
+		}
+		for i := range labels {
+			labels[i] = time.Time{}.Add(time.Duration(i) * s.Step).Format(format)
+		}
+	case s.unit() == Day:
+		first = int(s.Start.Weekday())
+		for i := range labels {
+			labels[i] = time.Weekday(i).String()[:3]
+		}
+	case s.unit() == Week:
+		_, wk := s.Start.ISOWeek()
+		if wk > periodicity {
+			wk = periodicity
+		}
+		first = wk - 1
+		for i := range labels {
+			labels[i] = fmt.Sprintf("W%02d", i+1)
+		}
+	case s.unit() == Month:
+		first = int(s.Start.Month()) - 1
+		for i := range labels {
+			labels[i] = time.Month(i + 1).String()[:3]
+		}
+	case s.unit() == Quarter:
+		first = (int(s.Start.Month()) - 1) / 3
+		for i := range labels {
+			labels[i] = fmt.Sprintf("Q%d", i+1)
+		}
+	}
+
+	for i := range phase {
+		phase[i] = (first + i) % periodicity
+	}
+	return
+}
+
+// SeriesResult is the result of decomposing a Series. The components are indexed by Times.
+type SeriesResult struct {
+	Result
+	Times []time.Time
+
+	// Phase is the seasonal phase of each observation. Labels[Phase[i]] is a human readable label of the phase (e.g. "Jan" or "Mon").
+	Phase  []int
+	Labels []string
+}
+
+// DecomposeSeries performs a STL decomposition on a Series. The periodicity is inferred from the frequency of the series (see Series.Periodicity).
+func DecomposeSeries(s Series, width int, m ModelType, opts ...Opt) SeriesResult {
+	periodicity, err := s.Periodicity()
+	if err != nil {
+		return SeriesResult{Result: Result{Err: err}}
+	}
+	res := Decompose(s.Values, periodicity, width, m, opts...)
+	if res.Err != nil {
+		return SeriesResult{Result: res}
+	}
+	phase, labels := s.phases(periodicity)
+	return SeriesResult{
+		Result: res,
+		Times:  s.Times(),
+		Phase:  phase,
+		Labels: labels,
+	}
+}
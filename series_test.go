@@ -0,0 +1,187 @@
+package stl
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func loadCO2(t testing.TB) []float64 {
+	f, err := os.Open("testdata/co2.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var data []float64
+	r := csv.NewReader(f)
+	r.Read() // read header
+	for rec, err := r.Read(); err == nil; rec, err = r.Read() {
+		if co2, err := strconv.ParseFloat(rec[0], 64); err == nil {
+			data = append(data, co2)
+		}
+	}
+	return data
+}
+
+func TestSeriesPeriodicity(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		s       Series
+		correct int
+		err     bool
+	}{
+		{Series{Start: start, Step: time.Hour}, 24, false},
+		{Series{Start: start, Step: 5 * time.Minute}, 288, false},
+		{Series{Start: start, Step: 24 * time.Hour}, 7, false},
+		{Series{Start: start, Unit: Day}, 7, false},
+		{Series{Start: start, Unit: Week}, 52, false},
+		{Series{Start: start, Unit: Month}, 12, false},
+		{Series{Start: start, Unit: Quarter}, 4, false},
+		{Series{Start: start, Unit: Month, Period: 24}, 24, false},
+		{Series{Start: start, Unit: Year}, 0, true},
+		{Series{Start: start, Step: 7 * time.Hour}, 0, true},
+		{Series{Start: start}, 0, true},
+	}
+	for i, c := range cases {
+		p, err := c.s.Periodicity()
+		switch {
+		case c.err && err == nil:
+			t.Errorf("Case %d: expected an error", i)
+		case !c.err && err != nil:
+			t.Errorf("Case %d: %v", i, err)
+		case p != c.correct:
+			t.Errorf("Case %d: expected periodicity %d. Got %d", i, c.correct, p)
+		}
+	}
+}
+
+func TestSeriesYearPeriod(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	weekly := Series{Start: start, Unit: Week}
+	if p, err := weekly.YearPeriod(); err != nil || math.Abs(p-52.1786) > 1e-4 {
+		t.Errorf("Expected a period of 52.1786. Got %v (%v)", p, err)
+	}
+	hourly := Series{Start: start, Step: time.Hour}
+	if p, err := hourly.YearPeriod(); err != nil || p != 8766 {
+		t.Errorf("Expected a period of 8766. Got %v (%v)", p, err)
+	}
+	if _, err := (Series{Start: start, Unit: Year}).YearPeriod(); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestSeriesPhases(t *testing.T) {
+	// starts on a Wednesday at 22:00
+	start := time.Date(2020, time.January, 1, 22, 0, 0, 0, time.UTC)
+
+	hourly := Series{Start: start, Step: time.Hour, Values: make([]float64, 4)}
+	phase, labels := hourly.phases(24)
+	if phase[0] != 22 || phase[1] != 23 || phase[2] != 0 || phase[3] != 1 {
+		t.Errorf("Unexpected hourly phases %v", phase)
+	}
+	if labels[22] != "22:00" {
+		t.Errorf("Unexpected hourly label %q", labels[22])
+	}
+
+	daily := Series{Start: start, Unit: Day, Values: make([]float64, 5)}
+	phase, labels = daily.phases(7)
+	if labels[phase[0]] != "Wed" || labels[phase[4]] != "Sun" {
+		t.Errorf("Unexpected daily phases %v", phase)
+	}
+
+	// observations a period apart are smoothed in the same cycle-subseries, so they must have the same phase,
+	// although 2020 has 53 ISO weeks
+	weekly := Series{Start: start, Unit: Week, Values: make([]float64, 110)}
+	phase, labels = weekly.phases(52)
+	if labels[phase[0]] != "W01" {
+		t.Errorf("Expected the first week to be W01. Got %v", labels[phase[0]])
+	}
+	for i, p := range phase {
+		if p != i%52 {
+			t.Errorf("Observation %d is in subseries %d. Got phase %d", i, i%52, p)
+		}
+	}
+
+	monthly := Series{Start: time.Date(2020, time.November, 30, 0, 0, 0, 0, time.UTC), Unit: Month, Values: make([]float64, 3)}
+	if got := monthly.Time(2); !got.Equal(time.Date(2021, time.January, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v", got)
+	}
+	phase, labels = monthly.phases(12)
+	if labels[phase[0]] != "Nov" || labels[phase[2]] != "Jan" {
+		t.Errorf("Unexpected monthly phases %v", phase)
+	}
+
+	// every observation of a series is in the cycle-subseries of its index
+	for _, s := range []Series{hourly, daily, monthly, {Start: start, Unit: Quarter, Values: make([]float64, 9)}} {
+		p, _ := s.Periodicity()
+		phase, _ = s.phases(p)
+		for i := range phase {
+			if phase[i] != (phase[0]+i)%p {
+				t.Errorf("%v: observation %d is in subseries %d. Got phase %d", s.Unit, i, (phase[0]+i)%p, phase[i])
+			}
+		}
+	}
+}
+
+func TestSeriesMonthEnds(t *testing.T) {
+	monthly := Series{Start: time.Date(2021, time.January, 31, 9, 30, 0, 0, time.UTC), Unit: Month, Values: make([]float64, 5)}
+	for i, expected := range []time.Time{
+		time.Date(2021, time.January, 31, 9, 30, 0, 0, time.UTC),
+		time.Date(2021, time.February, 28, 9, 30, 0, 0, time.UTC),
+		time.Date(2021, time.March, 31, 9, 30, 0, 0, time.UTC),
+		time.Date(2021, time.April, 30, 9, 30, 0, 0, time.UTC),
+		time.Date(2021, time.May, 31, 9, 30, 0, 0, time.UTC),
+	} {
+		if got := monthly.Time(i); !got.Equal(expected) {
+			t.Errorf("Observation %d: expected %v. Got %v", i, expected, got)
+		}
+	}
+	phase, labels := monthly.phases(12)
+	for i, expected := range []string{"Jan", "Feb", "Mar", "Apr", "May"} {
+		if labels[phase[i]] != expected || labels[phase[i]] != monthly.Time(i).Month().String()[:3] {
+			t.Errorf("Observation %d: expected %v. Got %v", i, expected, labels[phase[i]])
+		}
+	}
+
+	quarterly := Series{Start: time.Date(2020, time.August, 31, 0, 0, 0, 0, time.UTC), Unit: Quarter}
+	if got := quarterly.Time(2); !got.Equal(time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected quarterly time %v", got)
+	}
+	yearly := Series{Start: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC), Unit: Year}
+	if got := yearly.Time(1); !got.Equal(time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected yearly time %v", got)
+	}
+	if got := yearly.Time(4); !got.Equal(time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected yearly time %v", got)
+	}
+}
+
+func TestDecomposeSeries(t *testing.T) {
+	data := loadCO2(t)
+	s := Series{
+		Start:  time.Date(1959, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Unit:   Month,
+		Values: data,
+	}
+	res := DecomposeSeries(s, 35, Additive())
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if len(res.Times) != len(data) || len(res.Phase) != len(data) || len(res.Labels) != 12 {
+		t.Fatalf("Unexpected result lengths: %d times, %d phases, %d labels", len(res.Times), len(res.Phase), len(res.Labels))
+	}
+	if !res.Times[12].Equal(time.Date(1960, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v", res.Times[12])
+	}
+	if res.Labels[res.Phase[13]] != "Feb" {
+		t.Errorf("Expected Feb. Got %v", res.Labels[res.Phase[13]])
+	}
+
+	yearly := Series{Start: s.Start, Unit: Year, Values: data}
+	if res := DecomposeSeries(yearly, 35, Additive()); res.Err == nil {
+		t.Errorf("Expected an error when decomposing a yearly series")
+	}
+}
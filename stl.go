@@ -5,6 +5,7 @@ import (
 	"sort"
 
 	"github.com/chewxy/stl/loess"
+	"github.com/pkg/errors"
 )
 
 type state struct {
@@ -21,12 +22,22 @@ type state struct {
 	innerIter  int
 	robustIter int
 
-	detrend          []float64
+	detrend          []float64 // the detrended data, and later in each inner iteration the deseasonalized data, from which the trend is smoothed
 	extendedSeasonal []float64
 	deseasonalized   []float64
 
 	sstate  *loess.State
 	scstate *subcycleState
+
+	// for non-integer periods. periodicity is then the period rounded up.
+	period  float64
+	phstate *phaseState
+
+	fourier *fourierState // if not nil, the seasonal component is estimated with harmonics
+
+	trendSlope bool
+	lastPass   bool // the inner iteration is the last one of the decomposition
+	parallel   int  // number of goroutines to smooth the cycle-subseries with
 }
 
 // Result is the result of a decompositon
@@ -35,41 +46,80 @@ type Result struct {
 	Trend    []float64
 	Seasonal []float64
 	Resid    []float64
-	Err      error
+
+	// TrendSlope is the local slope of the trend per observation, if requested with WithTrendSlope.
+	// It is in the transformed scale of the model - for a multiplicative model it is the relative growth rate.
+	TrendSlope []float64
+
+	// Weights are the robustness weights of the final fit, in [0, 1]. Outliers have low weights.
+	// As in R, they are all 1 if the decomposition is not robust (see WithRobustIter).
+	Weights []float64
+
+	Err error
 }
 
 func newState(data []float64, periodicity, width int, opts ...Opt) *state {
-	s := &state{
-		periodicity: periodicity,
-		width:       width,
-		smoothFn:    loess.Linear,
+	return new(workspace).newState(data, Result{}, periodicity, width, opts...)
+}
 
-		sConf: DefaultSeasonal(width),
-		tConf: DefaultTrend(periodicity, width),
-		lConf: DefaultLowPass(periodicity),
+// newFractionalState creates a state for decomposing data with a non-integer period.
+func newFractionalState(data []float64, period float64, width int, opts ...Opt) *state {
+	s := newState(data, int(math.Ceil(period)), width, opts...)
+	s.period = period
+	s.phstate = newPhaseState(s.sConf, len(data), period)
+	s.phstate.parallel = s.parallel
+	return s
+}
 
-		// R interface sets these to be the default
-		innerIter:  2,
-		robustIter: 0,
-	}
-	s.Data = data
-	s.Trend = make([]float64, len(data))
-	s.Seasonal = make([]float64, len(data))
-	s.Resid = make([]float64, len(data))
-	s.weights = make([]float64, len(data))
-	s.extendedSeasonal = make([]float64, len(data)+2*periodicity)
-	s.detrend = make([]float64, len(data))
+// iterate runs the inner and outer (robust) loops of the decomposition.
+func (s *state) iterate() error {
+	var useResidualWeights bool
+	for o := 0; o <= s.robustIter; o++ {
+		useResidualWeights = o > 0
+		for i := 0; i < s.innerIter; i++ {
+			s.lastPass = o == s.robustIter && i == s.innerIter-1
+			s.doDetrend()
+			if s.fourier != nil {
+				if err := s.updateHarmonics(useResidualWeights); err != nil {
+					return errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to fit harmonics", o, i)
+				}
+				continue
+			}
+			if err := s.smoothSubcycles(useResidualWeights); err != nil {
+				return errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to smooth subcycles", o, i)
+			}
+			if err := s.removeSeasonality(); err != nil {
+				return errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to remove seasonality", o, i)
+			}
+			if err := s.updateSeasonalAndTrend(useResidualWeights); err != nil {
+				return errors.Wrapf(err, "Outer Iteration: %d, Inner Iteration %d - Failed to update seasonal and trend", o, i)
+			}
 
-	for i := range s.weights {
-		s.weights[i] = 1
+		}
+		s.updateWeights()
 	}
-	s.sstate = loess.NewWithExternal(s.tConf.Width, s.Trend, s.weights)
-	s.scstate = newSubcycleState(s.sConf, len(s.Data), s.periodicity, 1, 1)
+	updateResiduals(&s.Result)
+	for i := range s.Weights {
+		s.Weights[i] = 1
+		if s.robustIter > 0 {
+			s.Weights[i] = s.weights[i]
+		}
+	}
+	return nil
+}
 
-	for _, o := range opts {
-		o(s)
+// smoothTrend smooths the deseasonalized data into the trend. If the slope of the trend is requested (see WithTrendSlope), it is estimated in the last pass,
+// on the same windows and with the same kernel and robustness weights as the trend.
+func (s *state) smoothTrend() (err error) {
+	if !s.trendSlope || !s.lastPass {
+		s.Trend, err = loess.UnsafeSmooth(s.sstate, s.tConf.Width, s.tConf.Jump, s.tConf.Fn, s.Trend)
+		return err
 	}
-	return s
+	if len(s.TrendSlope) != len(s.Trend) {
+		s.TrendSlope = make([]float64, len(s.Trend))
+	}
+	s.Trend, err = loess.UnsafeSmooth(s.sstate, s.tConf.Width, s.tConf.Jump, s.tConf.Fn, s.Trend, loess.WithSlopes(s.TrendSlope))
+	return errors.Wrap(err, "Failed to estimate the slope of the trend")
 }
 
 func updateResiduals(r *Result) {
@@ -122,23 +172,34 @@ func (s *state) smoothSubcycles(useWeights bool) error {
 	if useWeights {
 		weights = s.weights
 	}
+	if s.phstate != nil {
+		return s.phstate.smoothSeasonal(s.detrend, weights, s.extendedSeasonal)
+	}
 	return s.scstate.smoothSeasonal(s.detrend, weights, s.extendedSeasonal)
 }
 
 func (s *state) removeSeasonality() (err error) {
-	passes := ma(ma(ma(s.extendedSeasonal, s.periodicity), s.periodicity), 3)
-	s.deseasonalized, err = loess.Smooth(passes, s.lConf.Width, s.lConf.Jump, s.lConf.Fn)
+	var passes []float64
+	if s.phstate != nil {
+		passes = ma(fma(fma(s.extendedSeasonal, s.period), s.period), 3)
+	} else {
+		passes = ma(ma(ma(s.extendedSeasonal, s.periodicity), s.periodicity), 3)
+	}
+	lopts := []loess.Option{loess.WithKernel(s.lConf.Kernel), loess.WithInterpolation(s.lConf.Interpolation)}
+	if s.lConf.Fast {
+		lopts = append(lopts, loess.WithFast())
+	}
+	s.deseasonalized, err = loess.Smooth(passes, s.lConf.Width, s.lConf.Jump, s.lConf.Fn, lopts...)
 	return err
 }
 
 func (s *state) updateSeasonalAndTrend(useWeights bool) (err error) {
+	// the extended seasonal component has one (rounded up) period on either side of the data
 	for i := range s.Seasonal {
-		s.Seasonal[i] = s.extendedSeasonal[s.periodicity+1] - s.deseasonalized[i]
-		s.Trend[i] = s.Data[i] - s.Seasonal[i]
+		s.Seasonal[i] = s.extendedSeasonal[s.periodicity+i] - s.deseasonalized[i]
+		s.detrend[i] = s.Data[i] - s.Seasonal[i]
 	}
-
-	s.Trend, err = loess.UnsafeSmooth(s.sstate, s.tConf.Width, s.tConf.Jump, s.tConf.Fn, s.Trend)
-	return
+	return s.smoothTrend()
 }
 
 // ma is a moving average
@@ -164,3 +225,27 @@ func ma(data []float64, window int) (retVal []float64) {
 	}
 	return retVal
 }
+
+// fma is a moving average over a non-integer window. The window spans floor(period)+1 points,
+// where the two end points share the fractional remainder, so that the weights sum to period.
+// When the period is an integer, this is the same as ma.
+func fma(data []float64, period float64) (retVal []float64) {
+	k := int(period)
+	frac := period - float64(k)
+	if frac < 1e-9 {
+		return ma(data, k)
+	}
+
+	retSize := len(data) - k
+	retVal = make([]float64, retSize)
+	end := (1 + frac) / 2
+	var sum float64
+	for i := 1; i < k; i++ {
+		sum += data[i]
+	}
+	for i := 0; i < retSize; i++ {
+		retVal[i] = (sum + end*(data[i]+data[i+k])) / period
+		sum += data[i+k] - data[i+1]
+	}
+	return retVal
+}
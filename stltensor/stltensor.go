@@ -0,0 +1,166 @@
+// Package stltensor performs STL decompositions of the series held in the rows or columns of a 2-D *tensor.Dense.
+//
+// It is a separate package so that package stl does not depend on gorgonia.org/tensor.
+package stltensor
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/chewxy/stl"
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Axis is the axis of a tensor along which the series lie.
+type Axis int
+
+const (
+	// Columns means each column of the tensor is a series.
+	Columns Axis = iota
+	// Rows means each row of the tensor is a series.
+	Rows
+)
+
+// Result is the result of decomposing every series of a tensor. The components have the same shape (and data order) as the tensor.
+type Result struct {
+	Trend    *tensor.Dense
+	Seasonal *tensor.Dense
+	Resid    *tensor.Dense
+
+	// Errs holds the error of the decomposition of each series, if any. The components of a series that failed are NaN.
+	Errs []error
+}
+
+// Decompose performs a STL decomposition of every series (column or row) of the 2-D float64 tensor t,
+// with the same periodicity, width, model and options. See stl.Decompose.
+//
+// The series are decomposed concurrently by a pool of workers (GOMAXPROCS of them if workers <= 0), each with its own stl.Decomposer.
+// Series that are contiguous in the data of the tensor (the rows of a row major tensor, or the columns of a column major tensor)
+// are decomposed in place, with their components written directly into the resulting tensors. As with stl.Decompose,
+// they are transformed in place by the model (and back), so t is modified while Decompose runs and must not be read or written concurrently.
+// Each of those series is saved in a per-worker buffer first, and restored when its decomposition ends, whether it succeeded or not,
+// so t is left as it was (to the bit, which transforming back would not guarantee). If the model transforms the components back
+// into new slices rather than in place, they are copied into the resulting tensors.
+//
+// Other series are copied into and out of per-worker buffers, one element at a time. That includes the columns of a row major tensor,
+// the usual layout of a tensor holding one series per column, so each of them, and each of its components, is copied once.
+// To decompose columns without the copies, store the tensor in column major order (see tensor.AsFortran).
+//
+// Views that are not contiguous are materialized first. An error is returned only if t is not a 2-D float64 tensor;
+// the errors of the individual series are in Result.Errs.
+func Decompose(t *tensor.Dense, axis Axis, periodicity, width int, m stl.ModelType, workers int, opts ...stl.Opt) (Result, error) {
+	if t.Dims() != 2 {
+		return Result{}, errors.Errorf("Expected a 2-D tensor. Got %v", t.Shape())
+	}
+	if t.Dtype() != tensor.Float64 {
+		return Result{}, errors.Errorf("Expected a tensor of float64. Got %v", t.Dtype())
+	}
+	if t.RequiresIterator() {
+		t = t.Materialize().(*tensor.Dense)
+	}
+
+	rows, cols := t.Shape()[0], t.Shape()[1]
+	data := t.Data().([]float64)
+	colMajor := t.DataOrder().IsColMajor()
+	newComponent := func() *tensor.Dense {
+		backing := make([]float64, rows*cols)
+		if colMajor {
+			return tensor.New(tensor.WithShape(rows, cols), tensor.AsFortran(backing))
+		}
+		return tensor.New(tensor.WithShape(rows, cols), tensor.WithBacking(backing))
+	}
+	retVal := Result{
+		Trend:    newComponent(),
+		Seasonal: newComponent(),
+		Resid:    newComponent(),
+	}
+	trend := retVal.Trend.Data().([]float64)
+	seasonal := retVal.Seasonal.Data().([]float64)
+	resid := retVal.Resid.Data().([]float64)
+
+	// the ith series is at data[i*seriesStride + j*stride] for j in [0, length)
+	strides := t.Strides()
+	count, length, seriesStride, stride := cols, rows, strides[1], strides[0]
+	if axis == Rows {
+		count, length, seriesStride, stride = rows, cols, strides[0], strides[1]
+	}
+	retVal.Errs = make([]error, count)
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := stl.NewDecomposer(periodicity, width, m, opts...)
+			var x, dst stl.Result
+			weights := make([]float64, length) // the weights are not returned, so one buffer serves every series
+			x.Data = make([]float64, length)   // a copy of the series, which is restored from it if it is decomposed in place
+			if stride != 1 {
+				dst = stl.Result{Trend: make([]float64, length), Seasonal: make([]float64, length), Resid: make([]float64, length), Weights: weights}
+			}
+			for i := range jobs {
+				off := i * seriesStride
+				if stride == 1 {
+					end := off + length
+					copy(x.Data, data[off:end])
+					res := d.DecomposeInto(data[off:end], stl.Result{Trend: trend[off:end], Seasonal: seasonal[off:end], Resid: resid[off:end], Weights: weights})
+					copy(data[off:end], x.Data)
+					if res.Err != nil {
+						retVal.Errs[i] = res.Err
+						fill(trend[off:end], seasonal[off:end], resid[off:end])
+						continue
+					}
+					// a model may transform the components back into new slices, rather than in place
+					place(trend[off:end], res.Trend)
+					place(seasonal[off:end], res.Seasonal)
+					place(resid[off:end], res.Resid)
+					continue
+				}
+
+				for j := range x.Data {
+					x.Data[j] = data[off+j*stride]
+				}
+				res := d.DecomposeInto(x.Data, dst)
+				if res.Err != nil {
+					retVal.Errs[i] = res.Err
+					res = stl.Result{Trend: dst.Trend, Seasonal: dst.Seasonal, Resid: dst.Resid}
+					fill(res.Trend, res.Seasonal, res.Resid)
+				}
+				for j := 0; j < length; j++ {
+					k := off + j*stride
+					trend[k] = res.Trend[j]
+					seasonal[k] = res.Seasonal[j]
+					resid[k] = res.Resid[j]
+				}
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return retVal, nil
+}
+
+// place copies a component into dst, unless it is already there.
+func place(dst, component []float64) {
+	if len(component) > 0 && &component[0] != &dst[0] {
+		copy(dst, component)
+	}
+}
+
+// fill fills the components of a failed decomposition with NaN.
+func fill(components ...[]float64) {
+	for _, c := range components {
+		for i := range c {
+			c[i] = math.NaN()
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package stltensor
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/chewxy/stl"
+	"gorgonia.org/tensor"
+)
+
+func loadCO2(t *testing.T) []float64 {
+	f, err := os.Open("../testdata/co2.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var data []float64
+	r := csv.NewReader(f)
+	r.Read() // read header
+	for rec, err := r.Read(); err == nil; rec, err = r.Read() {
+		if co2, err := strconv.ParseFloat(rec[0], 64); err == nil {
+			data = append(data, co2)
+		}
+	}
+	return data
+}
+
+// series returns n variations of the CO2 data.
+func series(t *testing.T, n int) [][]float64 {
+	data := loadCO2(t)
+	retVal := make([][]float64, n)
+	for i := range retVal {
+		retVal[i] = make([]float64, len(data))
+		for j := range data {
+			retVal[i][j] = data[j]*(1+0.01*float64(i)) + math.Sin(float64(i*j))
+		}
+	}
+	return retVal
+}
+
+func TestDecompose(t *testing.T) {
+	const n = 5
+	s := series(t, n)
+	length := len(s[0])
+
+	rowMajor := make([]float64, n*length) // series are rows
+	colMajor := make([]float64, n*length) // series are columns
+	for i := range s {
+		for j, v := range s[i] {
+			rowMajor[i*length+j] = v
+			colMajor[j*n+i] = v
+		}
+	}
+	cases := []struct {
+		name string
+		t    *tensor.Dense
+		axis Axis
+	}{
+		{"rows", tensor.New(tensor.WithShape(n, length), tensor.WithBacking(rowMajor)), Rows},
+		{"columns", tensor.New(tensor.WithShape(length, n), tensor.WithBacking(colMajor)), Columns},
+		{"fortran columns", tensor.New(tensor.WithShape(length, n), tensor.AsFortran(append([]float64(nil), colMajor...))), Columns},
+	}
+
+	for _, c := range cases {
+		res, err := Decompose(c.t, c.axis, 12, 35, stl.Additive(), 2, stl.WithRobustIter(1))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !res.Trend.Shape().Eq(c.t.Shape()) {
+			t.Fatalf("%s: expected the components to have shape %v. Got %v", c.name, c.t.Shape(), res.Trend.Shape())
+		}
+		for i := range s {
+			if res.Errs[i] != nil {
+				t.Fatalf("%s: series %d: %v", c.name, i, res.Errs[i])
+			}
+			X := append([]float64(nil), s[i]...)
+			expected := stl.Decompose(X, 12, 35, stl.Additive(), stl.WithRobustIter(1))
+			for j := 0; j < length; j++ {
+				var at []int
+				if c.axis == Rows {
+					at = []int{i, j}
+				} else {
+					at = []int{j, i}
+				}
+				trend, _ := res.Trend.At(at...)
+				seasonal, _ := res.Seasonal.At(at...)
+				resid, _ := res.Resid.At(at...)
+				if trend.(float64) != expected.Trend[j] || seasonal.(float64) != expected.Seasonal[j] || resid.(float64) != expected.Resid[j] {
+					t.Fatalf("%s: series %d differs from stl.Decompose at %d", c.name, i, j)
+				}
+			}
+		}
+	}
+
+	if _, err := Decompose(tensor.New(tensor.WithShape(4), tensor.WithBacking(make([]float64, 4))), Rows, 12, 35, stl.Additive(), 0); err == nil {
+		t.Errorf("Expected an error for a 1-D tensor")
+	}
+}
+
+func TestDecomposeRestores(t *testing.T) {
+	const n = 4
+	s := series(t, n)
+	length := len(s[0])
+	backing := make([]float64, 0, n*length)
+	for i := range s {
+		backing = append(backing, s[i]...)
+	}
+	backing[2*length] = 1000 // the decomposition of the third series fails after its data were transformed
+	original := append([]float64(nil), backing...)
+
+	mul := stl.Multiplicative()
+	m := stl.ModelType{
+		Fwd: mul.Fwd,
+		Bwd: func(a []float64) []float64 {
+			if a[0] > math.Log(500) {
+				panic("failed")
+			}
+			return mul.Bwd(a)
+		},
+	}
+	res, err := Decompose(tensor.New(tensor.WithShape(n, length), tensor.WithBacking(backing)), Rows, 12, 35, m, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, err := range res.Errs {
+		if (err != nil) != (i == 2) {
+			t.Errorf("Series %d: unexpected error %v", i, err)
+		}
+	}
+	for i := range backing {
+		if math.Float64bits(backing[i]) != math.Float64bits(original[i]) {
+			t.Fatalf("Expected the tensor to be left as it was. Got %v instead of %v at %d", backing[i], original[i], i)
+		}
+	}
+}
+
+func TestDecomposeNewSlices(t *testing.T) {
+	const n = 3
+	s := series(t, n)
+	length := len(s[0])
+	backing := make([]float64, 0, n*length)
+	for i := range s {
+		backing = append(backing, s[i]...)
+	}
+
+	// a model that transforms back into new slices, rather than in place
+	mul := stl.Multiplicative()
+	m := stl.ModelType{
+		Fwd: mul.Fwd,
+		Bwd: func(a []float64) []float64 {
+			return mul.Bwd(append([]float64(nil), a...))
+		},
+	}
+	res, err := Decompose(tensor.New(tensor.WithShape(n, length), tensor.WithBacking(backing)), Rows, 12, 35, m, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range s {
+		if res.Errs[i] != nil {
+			t.Fatalf("Series %d: %v", i, res.Errs[i])
+		}
+		expected := stl.Decompose(append([]float64(nil), s[i]...), 12, 35, m)
+		for j := 0; j < length; j++ {
+			trend, _ := res.Trend.At(i, j)
+			seasonal, _ := res.Seasonal.At(i, j)
+			resid, _ := res.Resid.At(i, j)
+			if trend.(float64) != expected.Trend[j] || seasonal.(float64) != expected.Seasonal[j] || resid.(float64) != expected.Resid[j] {
+				t.Fatalf("Series %d differs from stl.Decompose at %d", i, j)
+			}
+		}
+	}
+}
@@ -4,19 +4,19 @@ import (
 	"math"
 
 	"github.com/chewxy/stl/loess"
-	"github.com/pkg/errors"
-	"gorgonia.org/tensor"
-	"gorgonia.org/tensor/native"
 )
 
 type subcycleState struct {
-	// (2, P, L) tensor - first 2 are rawdata and weight
-	// P: period length/periodicity
-	// L cycle length = periods+1
-	data     *tensor.Dense
-	smoothed *tensor.Dense // (P, L+Fwd+Bwd+1)
+	// (2, P, L) array, flattened in row major order - first 2 are rawdata and weight
+	// P: period length/periodicity - the number of cycle-subseries
+	// L: cycle length - the number of cycles, which is periods+1 if the last cycle is incomplete
+	data     []float64
+	smoothed []float64 // (P, L+Fwd+Bwd), flattened in row major order
+
+	cycleLength, smoothedLength int
 
 	periodicity, periods, rem, fwd, bwd int
+	parallel                            int // number of goroutines to smooth the subseries with
 
 	Config // for any loess smoothing
 }
@@ -26,14 +26,17 @@ type subcycleState struct {
 func newSubcycleState(conf Config, size, periodicity, fwd, bwd int) *subcycleState {
 	periods := size / periodicity
 	rem := size % periodicity
-	cycleLength := periodicity + 1
+	cycleLength := periods + 1
 	if rem == 0 {
-		cycleLength = periodicity
+		cycleLength = periods
 	}
 	smoothedLength := cycleLength + fwd + bwd
 	retVal := &subcycleState{
-		data:     tensor.New(tensor.WithShape(2, periods, cycleLength), tensor.Of(tensor.Float64), tensor.WithEngine(tensor.Float64Engine{})),
-		smoothed: tensor.New(tensor.WithShape(periods, smoothedLength), tensor.Of(tensor.Float64), tensor.WithEngine(tensor.Float64Engine{})),
+		data:     make([]float64, 2*periodicity*cycleLength),
+		smoothed: make([]float64, periodicity*smoothedLength),
+
+		cycleLength:    cycleLength,
+		smoothedLength: smoothedLength,
 
 		periodicity: periodicity,
 		periods:     periods,
@@ -47,71 +50,83 @@ func newSubcycleState(conf Config, size, periodicity, fwd, bwd int) *subcycleSta
 	return retVal
 }
 
+// reset clears the workspace for the decomposition of another series of the same shape, with the given configuration.
+func (s *subcycleState) reset(conf Config) {
+	for i := range s.data {
+		s.data[i] = 0
+	}
+	for i := range s.smoothed {
+		s.smoothed[i] = 0
+	}
+	s.Config = conf
+}
+
+// smoothSeasonal smooths the cycle-subseries of X, writing the extended result into retVal,
+// which must have len(X) + (bwd+fwd)*periodicity elements: the ith observation is at retVal[i+bwd*periodicity].
 func (s *subcycleState) smoothSeasonal(X []float64, weights []float64, retVal []float64) error {
 	s.setupWorkspace(X, weights)
 	if err := s.computeSmoothedSubSeries(); err != nil {
 		return err
 	}
-	copy(retVal, s.smoothed.Data().([]float64))
+	for p := 0; p < s.periodicity; p++ {
+		_, _, smoothed := s.subseries(p)
+		for j, v := range smoothed[:s.length(p)+s.bwd+s.fwd] {
+			retVal[j*s.periodicity+p] = v
+		}
+	}
 	return nil
 }
 
-// setupWorkspace sets up the workspace by copying the data to the tensor.
-// Due to the periodicity in question, a direct copy wouldn't really work
-func (s *subcycleState) setupWorkspace(X, weights []float64) {
-	// populate data
-	// strides := s.data.Strides()
-	// raw := s.data.Data().([]float64)
-	// copy(raw[0:strides[0]], X)
-	// copy(raw[strides[0]:], weights)
-
-	var xxx [][][]float64
-	var err error
-	if xxx, err = native.Tensor3F64(s.data); err != nil {
-		panic(errors.Wrap(err, "setup workspace"))
+// length returns the number of observations in the pth subseries.
+func (s *subcycleState) length(p int) int {
+	if p < s.rem {
+		return s.periods + 1
 	}
+	return s.periods
+}
 
-	for p := 0; p < s.periods; p++ {
-		l := s.periodicity
-		if p < s.rem {
-			l = s.periodicity + 1
-		}
-		for i := 0; i < l && i*s.periodicity+p < len(X); i++ {
-			xxx[0][p][i] = X[i*s.periodicity+p]
+// subseries returns the data and weights of the pth subseries, and the slice its smoothed values are written to.
+func (s *subcycleState) subseries(p int) (data, weights, smoothed []float64) {
+	plane := s.periodicity * s.cycleLength
+	data = s.data[p*s.cycleLength : (p+1)*s.cycleLength]
+	weights = s.data[plane+p*s.cycleLength : plane+(p+1)*s.cycleLength]
+	smoothed = s.smoothed[p*s.smoothedLength : (p+1)*s.smoothedLength]
+	return
+}
+
+// setupWorkspace sets up the workspace by copying the data to the subseries.
+// The pth subseries holds the observations p, p+periodicity, p+2*periodicity, and so on.
+func (s *subcycleState) setupWorkspace(X, weights []float64) {
+	for p := 0; p < s.periodicity; p++ {
+		data, w, _ := s.subseries(p)
+		for i := 0; i < s.length(p); i++ {
+			data[i] = X[i*s.periodicity+p]
+			w[i] = 1
 			if len(weights) > 0 {
-				xxx[1][p][i] = weights[i*s.periodicity+p]
+				w[i] = weights[i*s.periodicity+p]
 			}
 		}
 	}
 }
 
-func (s *subcycleState) computeSmoothedSubSeries() (err error) {
-	var xxx [][][]float64
-	var xx [][]float64
-	if xxx, err = native.Tensor3F64(s.data); err != nil {
-		return errors.Wrap(err, "Compute Smoothed Series")
-	}
-	if xx, err = native.MatrixF64(s.smoothed); err != nil {
-		return errors.Wrap(err, "Compute Smoothed Series")
-	}
-
-	periods := s.periods
-	for p := 0; p < periods; p++ {
-		data := xxx[0][p]
-		weights := xxx[1][p]
-		smoothed := xx[p]
-
-		s.do(weights, data, smoothed)
-	}
-	return nil
+func (s *subcycleState) computeSmoothedSubSeries() error {
+	return forEach(s.periodicity, s.parallel, func(p int) error {
+		data, weights, smoothed := s.subseries(p)
+		l := s.length(p)
+		return s.do(weights[:l], data[:l], smoothed[:l+s.bwd+s.fwd])
+	})
 }
 
-func (s *subcycleState) do(weights, data, smoothed []float64) {
+// do smooths a subseries into smoothed[bwd:bwd+len(data)], and extrapolates it bwd points backwards and fwd points forwards.
+func (s *subcycleState) do(weights, data, smoothed []float64) error {
 	cycleLength := float64(len(data))
 	l := loess.NewWithExternal(s.Width, data, weights)
+	l.SetKernel(s.Kernel)
+	l.SetFast(s.Fast)
+	l.SetInterpolation(s.Interpolation)
 
-	if _, err := loess.UnsafeSmooth(l, s.Config.Width, s.Config.Jump, loess.Linear, smoothed[1:]); err != nil {
-		panic(err)
+	if _, err := loess.UnsafeSmooth(l, s.Config.Width, s.Config.Jump, s.Fn, smoothed[s.bwd:]); err != nil {
+		return err
 	}
 
 	var left, right float64
@@ -122,7 +137,7 @@ func (s *subcycleState) do(weights, data, smoothed []float64) {
 
 	for i := 1; i <= s.bwd; i++ {
 		j := -float64(i)
-		point, err := loess.Regress(l, loess.Linear, j, left, right)
+		point, err := loess.Regress(l, s.Fn, j, left, right)
 		if err != nil {
 			smoothed[leftVal-i] = smoothed[leftVal]
 		} else {
@@ -137,11 +152,12 @@ func (s *subcycleState) do(weights, data, smoothed []float64) {
 
 	for i := 1; i <= s.fwd; i++ {
 		j := float64(i)
-		point, err := loess.Regress(l, loess.Linear, right+j, left, right)
+		point, err := loess.Regress(l, s.Fn, right+j, left, right)
 		if err != nil {
 			smoothed[rightVal+i] = smoothed[rightVal]
 		} else {
 			smoothed[rightVal+i] = point
 		}
 	}
+	return nil
 }